@@ -1,6 +1,9 @@
 package main
 
-import "strconv"
+import (
+	"math"
+	"strconv"
+)
 
 // Node is the common interface implemented by all AST nodes.
 type Node interface {
@@ -8,12 +11,30 @@ type Node interface {
 	NodeType() string
 }
 
+// NodeBase carries the source position of a node. It is embedded in every
+// declaration, statement and expression node.
+type NodeBase struct {
+	Line int // 1-based; 0 when the node was not built from source
+}
+
+// Pos returns the node's 1-based source line.
+func (b *NodeBase) Pos() int { return b.Line }
+
+// lineOf returns n's source line, or 0 for nodes without a position.
+func lineOf(n Node) int {
+	if p, ok := n.(interface{ Pos() int }); ok {
+		return p.Pos()
+	}
+	return 0
+}
+
 // ===== Program / Top-level =====
 
 type Program struct {
 	// e.g. "program { ... }"
 	Declarations []*VarDecl    // top-level variable declarations
 	Methods      []*MethodDecl // top-level method (function) declarations
+	Symbols      Env           // top-level scope, filled in by the builder
 }
 
 func (p *Program) NodeType() string { return "Program" }
@@ -25,7 +46,16 @@ type TypeKind int
 const (
 	TypeVoid TypeKind = iota
 	TypeBool
-	TypeInteger
+	TypeInteger // 64-bit
+
+	// sized integers
+	TypeInt8
+	TypeInt16
+	TypeInt32
+	TypeInt64
+
+	TypeString
+	TypeChar
 )
 
 func (t TypeKind) String() string {
@@ -36,11 +66,71 @@ func (t TypeKind) String() string {
 		return "bool"
 	case TypeInteger:
 		return "integer"
+	case TypeInt8:
+		return "int8"
+	case TypeInt16:
+		return "int16"
+	case TypeInt32:
+		return "int32"
+	case TypeInt64:
+		return "int64"
+	case TypeString:
+		return "string"
+	case TypeChar:
+		return "char"
 	default:
 		return "unknown"
 	}
 }
 
+// Bits returns the width of an integer type (integer is 64-bit), or 0 for
+// non-integer types.
+func (t TypeKind) Bits() int {
+	switch t {
+	case TypeInt8:
+		return 8
+	case TypeInt16:
+		return 16
+	case TypeInt32:
+		return 32
+	case TypeInteger, TypeInt64:
+		return 64
+	default:
+		return 0
+	}
+}
+
+// IsInteger reports whether t is integer or one of the sized integer types.
+func (t TypeKind) IsInteger() bool { return t.Bits() > 0 }
+
+// Range returns the smallest and largest values an integer type holds.
+func (t TypeKind) Range() (min, max int) {
+	switch t.Bits() {
+	case 8:
+		return math.MinInt8, math.MaxInt8
+	case 16:
+		return math.MinInt16, math.MaxInt16
+	case 32:
+		return math.MinInt32, math.MaxInt32
+	default:
+		return math.MinInt64, math.MaxInt64
+	}
+}
+
+// Size returns how many bytes a variable of type t occupies in its slot.
+func (t TypeKind) Size() int {
+	switch {
+	case t.IsInteger():
+		return t.Bits() / 8
+	case t == TypeBool, t == TypeChar:
+		return 1
+	case t == TypeString:
+		return 8 // the address of the bytes
+	default:
+		return 0
+	}
+}
+
 // A simple wrapper node for a type if you want a Node for types.
 type TypeNode struct {
 	Kind TypeKind
@@ -61,6 +151,7 @@ func (id Identifier) String() string   { return string(id) }
 //
 //	<type> <identifier> = <expression> ;
 type VarDecl struct {
+	NodeBase
 	Type  *TypeNode
 	Name  Identifier
 	Value Expr
@@ -70,6 +161,7 @@ func (d *VarDecl) NodeType() string { return "VarDecl" }
 
 // Parameter corresponds to `parameter` (type + identifier)
 type Parameter struct {
+	NodeBase
 	Type *TypeNode
 	Name Identifier
 }
@@ -82,6 +174,7 @@ func (p *Parameter) NodeType() string { return "Parameter" }
 //   <type_or_void> <identifier> "(" commaSeparatedOptional(parameter) ")" ( block | "extern" ";" )
 
 type MethodDecl struct {
+	NodeBase
 	Return *TypeNode // pointer so we can represent void (TypeVoid) or nil if desired
 	Name   Identifier
 	Params []*Parameter
@@ -99,6 +192,7 @@ type Stmt interface {
 }
 
 type Block struct {
+	NodeBase
 	Declarations []*VarDecl // declarations local to the block (corresponds to repeat(field("declaration", ...)))
 	Stmts        []Stmt
 }
@@ -107,6 +201,7 @@ func (b *Block) NodeType() string { return "Block" }
 func (b *Block) isStmt()          {}
 
 type Assignment struct {
+	NodeBase
 	Target Identifier // field("identifier", $.identifier)
 	Value  Expr       // field("value", $._expression)
 }
@@ -115,6 +210,7 @@ func (a *Assignment) NodeType() string { return "Assignment" }
 func (a *Assignment) isStmt()          {}
 
 type ExprStmt struct {
+	NodeBase
 	Expr Expr // used for method_call followed by ';' or any expression statement
 }
 
@@ -123,6 +219,7 @@ func (e *ExprStmt) isStmt()          {}
 
 // ReturnStmt corresponds to `return` optional expression + ';'
 type ReturnStmt struct {
+	NodeBase
 	Value Expr // nil if no value
 }
 
@@ -130,6 +227,7 @@ func (r *ReturnStmt) NodeType() string { return "ReturnStmt" }
 func (r *ReturnStmt) isStmt()          {}
 
 type IfStmt struct {
+	NodeBase
 	Cond Expr
 	Then *Block
 	Else *Block // nil if absent
@@ -139,6 +237,7 @@ func (i *IfStmt) NodeType() string { return "IfStmt" }
 func (i *IfStmt) isStmt()          {}
 
 type WhileStmt struct {
+	NodeBase
 	Cond Expr
 	Body *Block
 }
@@ -146,6 +245,22 @@ type WhileStmt struct {
 func (w *WhileStmt) NodeType() string { return "WhileStmt" }
 func (w *WhileStmt) isStmt()          {}
 
+// ForStmt corresponds to `for_statement`:
+//
+//	for ( <type> <identifier> = <init> to <bound> ) <block>
+//
+// The loop variable takes the values init, init+1, ... while it is below
+// bound, and is only visible inside Body.
+type ForStmt struct {
+	NodeBase
+	Var   *VarDecl // loop variable and its initial value
+	Bound Expr
+	Body  *Block
+}
+
+func (f *ForStmt) NodeType() string { return "ForStmt" }
+func (f *ForStmt) isStmt()          {}
+
 // VarDecl can be stored in Block.Declarations and top-level Program.Declarations.
 // If you want a single AST node type for declaration statements (rather than a dedicated VarDecl),
 // the above structure already models it directly.
@@ -158,6 +273,7 @@ type Expr interface {
 }
 
 type IntLiteral struct {
+	NodeBase
 	Value int
 	Type  TypeKind
 }
@@ -166,6 +282,7 @@ func (n *IntLiteral) NodeType() string { return "IntLiteral" }
 func (n *IntLiteral) isExpr()          {}
 
 type BoolLiteral struct {
+	NodeBase
 	Value bool
 	Type  TypeKind
 }
@@ -173,7 +290,29 @@ type BoolLiteral struct {
 func (n *BoolLiteral) NodeType() string { return "BoolLiteral" }
 func (n *BoolLiteral) isExpr()          {}
 
+// StringLiteral is a double-quoted string; Value holds the decoded text,
+// escapes already applied.
+type StringLiteral struct {
+	NodeBase
+	Value string
+	Type  TypeKind
+}
+
+func (n *StringLiteral) NodeType() string { return "StringLiteral" }
+func (n *StringLiteral) isExpr()          {}
+
+// CharLiteral is a single-quoted character, escapes already applied.
+type CharLiteral struct {
+	NodeBase
+	Value rune
+	Type  TypeKind
+}
+
+func (n *CharLiteral) NodeType() string { return "CharLiteral" }
+func (n *CharLiteral) isExpr()          {}
+
 type IdentExpr struct {
+	NodeBase
 	Name Identifier
 }
 
@@ -200,6 +339,7 @@ func (op UnaryOp) String() string {
 }
 
 type UnaryExpr struct {
+	NodeBase
 	Op   UnaryOp
 	Expr Expr
 	Type TypeKind
@@ -217,9 +357,11 @@ const (
 	BinSub
 	BinMul
 	BinDiv
+	BinMod
 
 	// relational
 	BinEq
+	BinNeq
 	BinLT
 	BinGT
 
@@ -238,8 +380,12 @@ func (op BinOp) String() string {
 		return "*"
 	case BinDiv:
 		return "/"
+	case BinMod:
+		return "%"
 	case BinEq:
 		return "=="
+	case BinNeq:
+		return "!="
 	case BinLT:
 		return "<"
 	case BinGT:
@@ -254,6 +400,7 @@ func (op BinOp) String() string {
 }
 
 type BinaryExpr struct {
+	NodeBase
 	Left  Expr
 	Op    BinOp
 	Right Expr
@@ -265,6 +412,7 @@ func (n *BinaryExpr) isExpr()          {}
 
 // CallExpr / Method call: identifier "(" args... ")"
 type CallExpr struct {
+	NodeBase
 	Callee Identifier
 	Args   []Expr
 	Type   TypeKind
@@ -275,6 +423,7 @@ func (n *CallExpr) isExpr()          {}
 
 // Parenthesized expression (explicit in grammar as "(" _expression ")")
 type ParenExpr struct {
+	NodeBase
 	Inner Expr
 }
 
@@ -316,11 +465,13 @@ func (p *Program) String() string {
 }
 
 // simple debug helpers
-func (i *IntLiteral) GoString() string  { return strconv.Itoa(i.Value) }
-func (b *BoolLiteral) GoString() string { return strconv.FormatBool(b.Value) }
-func (id *IdentExpr) GoString() string  { return string(id.Name) }
-func (p *ParenExpr) GoString() string   { return "(" + p.Inner.NodeType() + ")" }
-func (c *CallExpr) GoString() string    { return string(c.Callee) + "(...)" }
+func (i *IntLiteral) GoString() string    { return strconv.Itoa(i.Value) }
+func (b *BoolLiteral) GoString() string   { return strconv.FormatBool(b.Value) }
+func (s *StringLiteral) GoString() string { return strconv.Quote(s.Value) }
+func (c *CharLiteral) GoString() string   { return strconv.QuoteRune(c.Value) }
+func (id *IdentExpr) GoString() string    { return string(id.Name) }
+func (p *ParenExpr) GoString() string     { return "(" + p.Inner.NodeType() + ")" }
+func (c *CallExpr) GoString() string      { return string(c.Callee) + "(...)" }
 func (b *BinaryExpr) GoString() string {
 	return "(" + b.Left.NodeType() + " " + b.Op.String() + " " + b.Right.NodeType() + ")"
 }
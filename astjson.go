@@ -0,0 +1,155 @@
+package main
+
+import "encoding/json"
+
+// ASTToJSON serializes p for tools such as editor plugins. Every node is an
+// object with a "kind" (its NodeType) and, except for the program, the
+// "line" it starts on. The other keys are the node's children and
+// attributes, named after the AST fields:
+//
+//	Program:       declarations, methods
+//	VarDecl:       type, name, value
+//	Parameter:     type, name
+//	MethodDecl:    return, name, params, extern, body (null when extern)
+//	Block:         declarations, statements
+//	Assignment:    target, value
+//	ExprStmt:      expr
+//	ReturnStmt:    value (null when absent)
+//	IfStmt:        cond, then, else (null when absent)
+//	WhileStmt:     cond, body
+//	ForStmt:       var, bound, body
+//	IntLiteral:    value, type
+//	BoolLiteral:   value, type
+//	StringLiteral: value (decoded, without quotes), type
+//	CharLiteral:   value (the character as a string), type
+//	IdentExpr:     name
+//	UnaryExpr:     op, expr, type
+//	BinaryExpr:    op, left, right, type
+//	CallExpr:      callee, args
+//	ParenExpr:     inner
+//
+// Types and operators are written as in the source ("integer", "!=", ...).
+// The "type" of an expression is the one the builder gave it.
+// Keys come out sorted, so the output for a given AST is always the same.
+func ASTToJSON(p *Program) ([]byte, error) {
+	return json.MarshalIndent(jsonNode(p), "", "  ")
+}
+
+type jsonObject map[string]any
+
+func jsonNode(n Node) any {
+	var obj jsonObject
+	switch n := n.(type) {
+	case *Program:
+		return jsonObject{
+			"kind":         n.NodeType(),
+			"declarations": jsonDecls(n.Declarations),
+			"methods":      jsonMethods(n.Methods),
+		}
+	case *VarDecl:
+		obj = jsonObject{"type": jsonType(n.Type), "name": n.Name, "value": jsonExpr(n.Value)}
+	case *Parameter:
+		obj = jsonObject{"type": jsonType(n.Type), "name": n.Name}
+	case *MethodDecl:
+		params := make([]any, 0, len(n.Params))
+		for _, p := range n.Params {
+			params = append(params, jsonNode(p))
+		}
+		obj = jsonObject{
+			"return": jsonType(n.Return),
+			"name":   n.Name,
+			"params": params,
+			"extern": n.Extern,
+			"body":   jsonBlock(n.Body),
+		}
+	case *Block:
+		stmts := make([]any, 0, len(n.Stmts))
+		for _, st := range n.Stmts {
+			stmts = append(stmts, jsonNode(st))
+		}
+		obj = jsonObject{"declarations": jsonDecls(n.Declarations), "statements": stmts}
+	case *Assignment:
+		obj = jsonObject{"target": n.Target, "value": jsonExpr(n.Value)}
+	case *ExprStmt:
+		obj = jsonObject{"expr": jsonExpr(n.Expr)}
+	case *ReturnStmt:
+		obj = jsonObject{"value": jsonExpr(n.Value)}
+	case *IfStmt:
+		obj = jsonObject{"cond": jsonExpr(n.Cond), "then": jsonBlock(n.Then), "else": jsonBlock(n.Else)}
+	case *WhileStmt:
+		obj = jsonObject{"cond": jsonExpr(n.Cond), "body": jsonBlock(n.Body)}
+	case *ForStmt:
+		var v any
+		if n.Var != nil {
+			v = jsonNode(n.Var)
+		}
+		obj = jsonObject{"var": v, "bound": jsonExpr(n.Bound), "body": jsonBlock(n.Body)}
+	case *IntLiteral:
+		obj = jsonObject{"value": n.Value, "type": n.Type.String()}
+	case *BoolLiteral:
+		obj = jsonObject{"value": n.Value, "type": n.Type.String()}
+	case *StringLiteral:
+		obj = jsonObject{"value": n.Value, "type": n.Type.String()}
+	case *CharLiteral:
+		obj = jsonObject{"value": string(n.Value), "type": n.Type.String()}
+	case *IdentExpr:
+		obj = jsonObject{"name": n.Name}
+	case *UnaryExpr:
+		obj = jsonObject{"op": n.Op.String(), "expr": jsonExpr(n.Expr), "type": n.Type.String()}
+	case *BinaryExpr:
+		obj = jsonObject{"op": n.Op.String(), "left": jsonExpr(n.Left), "right": jsonExpr(n.Right), "type": n.Type.String()}
+	case *CallExpr:
+		args := make([]any, 0, len(n.Args))
+		for _, a := range n.Args {
+			args = append(args, jsonExpr(a))
+		}
+		obj = jsonObject{"callee": n.Callee, "args": args}
+	case *ParenExpr:
+		obj = jsonObject{"inner": jsonExpr(n.Inner)}
+	default:
+		return nil
+	}
+	obj["kind"] = n.NodeType()
+	obj["line"] = lineOf(n)
+	return obj
+}
+
+// The helpers below turn nil children into JSON null rather than into a
+// typed nil inside a Node.
+
+func jsonType(t *TypeNode) any {
+	if t == nil {
+		return nil
+	}
+	return t.Kind.String()
+}
+
+func jsonBlock(b *Block) any {
+	if b == nil {
+		return nil
+	}
+	return jsonNode(b)
+}
+
+func jsonExpr(e Expr) any {
+	if e == nil {
+		return nil
+	}
+	return jsonNode(e)
+}
+
+func jsonDecls(decls []*VarDecl) []any {
+	out := make([]any, 0, len(decls))
+	for _, d := range decls {
+		out = append(out, jsonNode(d))
+	}
+	return out
+}
+
+func jsonMethods(methods []*MethodDecl) []any {
+	out := make([]any, 0, len(methods))
+	for _, m := range methods {
+		out = append(out, jsonNode(m))
+	}
+	return out
+}
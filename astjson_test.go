@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestASTToJSON(t *testing.T) {
+	// integer x = 1; integer inc(integer y) { return y + 1; }
+	p := &Program{
+		Declarations: []*VarDecl{{
+			NodeBase: NodeBase{Line: 2},
+			Type:     &TypeNode{Kind: TypeInteger},
+			Name:     "x",
+			Value:    &IntLiteral{NodeBase: NodeBase{Line: 2}, Value: 1, Type: TypeInteger},
+		}},
+		Methods: []*MethodDecl{{
+			NodeBase: NodeBase{Line: 3},
+			Return:   &TypeNode{Kind: TypeInteger},
+			Name:     "inc",
+			Params:   []*Parameter{{NodeBase: NodeBase{Line: 3}, Type: &TypeNode{Kind: TypeInteger}, Name: "y"}},
+			Body: &Block{NodeBase: NodeBase{Line: 3}, Stmts: []Stmt{
+				&ReturnStmt{NodeBase: NodeBase{Line: 4}, Value: &BinaryExpr{
+					NodeBase: NodeBase{Line: 4},
+					Left:     &IdentExpr{NodeBase: NodeBase{Line: 4}, Name: "y"},
+					Op:       BinAdd,
+					Right:    &IntLiteral{NodeBase: NodeBase{Line: 4}, Value: 1, Type: TypeInteger},
+					Type:     TypeInteger,
+				}},
+			}},
+		}},
+	}
+
+	out, err := ASTToJSON(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "declarations": [
+    {
+      "kind": "VarDecl",
+      "line": 2,
+      "name": "x",
+      "type": "integer",
+      "value": {
+        "kind": "IntLiteral",
+        "line": 2,
+        "type": "integer",
+        "value": 1
+      }
+    }
+  ],
+  "kind": "Program",
+  "methods": [
+    {
+      "body": {
+        "declarations": [],
+        "kind": "Block",
+        "line": 3,
+        "statements": [
+          {
+            "kind": "ReturnStmt",
+            "line": 4,
+            "value": {
+              "kind": "BinaryExpr",
+              "left": {
+                "kind": "IdentExpr",
+                "line": 4,
+                "name": "y"
+              },
+              "line": 4,
+              "op": "+",
+              "right": {
+                "kind": "IntLiteral",
+                "line": 4,
+                "type": "integer",
+                "value": 1
+              },
+              "type": "integer"
+            }
+          }
+        ]
+      },
+      "extern": false,
+      "kind": "MethodDecl",
+      "line": 3,
+      "name": "inc",
+      "params": [
+        {
+          "kind": "Parameter",
+          "line": 3,
+          "name": "y",
+          "type": "integer"
+        }
+      ],
+      "return": "integer"
+    }
+  ]
+}`
+	if string(out) != want {
+		t.Errorf("JSON mismatch:\n got %s\nwant %s", out, want)
+	}
+}
+
+func TestRunEmitJSON(t *testing.T) {
+	path := writeSource(t, "prog.ctds", sampleProgram)
+	jsonPath := strings.TrimSuffix(path, ".ctds") + ".ast.json"
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-emit=json", "-no-sint", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d, stderr:\n%s", code, stderr.String())
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatalf("expected %s to be written: %v", jsonPath, err)
+	}
+	var got struct {
+		Kind    string `json:"kind"`
+		Methods []struct {
+			Name string `json:"name"`
+		} `json:"methods"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+	if got.Kind != "Program" || len(got.Methods) != 2 || got.Methods[0].Name != "inc" {
+		t.Errorf("unexpected AST JSON: %+v", got)
+	}
+	if strings.Contains(stdout.String(), "program {") {
+		t.Errorf("expected no text dump with -emit=json, got:\n%s", stdout.String())
+	}
+}
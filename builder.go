@@ -2,20 +2,41 @@ package main
 
 import (
 	"fmt"
+	"strings"
+	"unicode/utf8"
+
 	sitter "github.com/tree-sitter/go-tree-sitter"
 )
 
 // BuildAST takes a CST node (root of a parsed source file) and returns our AST.
 func BuildAST(root *sitter.Node, src []byte) (*Program, error) {
+	p, errs := buildAST(root, src, false, nil)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return p, nil
+}
+
+// BuildASTPartial is BuildAST for trees that contain syntax errors.
+// Top-level declarations and methods that contain ERROR/MISSING nodes are
+// skipped (report them with syntaxErrors), and items that fail to build
+// are dropped with their error collected instead of aborting the build.
+func BuildASTPartial(root *sitter.Node, src []byte) (*Program, []error) {
+	return buildAST(root, src, true, nil)
+}
+
+// buildAST is BuildAST or, with partial set, BuildASTPartial, logging the
+// symbols it declares to log (which may be nil).
+func buildAST(root *sitter.Node, src []byte, partial bool, log *logger) (*Program, []error) {
 	if root.Kind() != "source_file" {
-		return nil, fmt.Errorf("expected root to be source_file, got %s", root.Kind())
+		return nil, []error{fmt.Errorf("expected root to be source_file, got %s", root.Kind())}
 	}
 
 	// source_file -> program
 	if root.ChildCount() == 0 {
-		return nil, fmt.Errorf("empty source file")
+		return nil, []error{fmt.Errorf("empty source file")}
 	}
-	return buildProgram(root.Child(0), src)
+	return buildProgram(root.Child(0), src, partial, log)
 }
 
 // ----------------------------------------------------------------------
@@ -29,39 +50,93 @@ func text(node *sitter.Node, src []byte) string {
 	return string(src[node.StartByte():node.EndByte()])
 }
 
+// line returns the 1-based source line where node starts.
+func line(node *sitter.Node) int {
+	return int(node.StartPosition().Row) + 1
+}
+
+// at records node's position for the AST node built from it.
+func at(node *sitter.Node) NodeBase {
+	return NodeBase{Line: line(node)}
+}
+
+// syntaxErrors reports every ERROR and MISSING node under n, in source order.
+func syntaxErrors(n *sitter.Node, src []byte) []error {
+	if n == nil || !n.HasError() {
+		return nil
+	}
+	pos := n.StartPosition()
+	if n.IsMissing() {
+		return []error{fmt.Errorf("line %d:%d: missing %s", pos.Row+1, pos.Column+1, n.Kind())}
+	}
+	if n.IsError() {
+		snippet := strings.SplitN(text(n, src), "\n", 2)[0]
+		return []error{fmt.Errorf("line %d:%d: syntax error near %q", pos.Row+1, pos.Column+1, snippet)}
+	}
+	var errs []error
+	for i := uint(0); i < n.ChildCount(); i++ {
+		errs = append(errs, syntaxErrors(n.Child(i), src)...)
+	}
+	return errs
+}
+
 // ----------------------------------------------------------------------
 // Builders
 // ----------------------------------------------------------------------
 
-func buildProgram(n *sitter.Node, src []byte) (*Program, error) {
-	if n.Kind() != "program" {
-		return nil, fmt.Errorf("expected program node, got %s", n.Kind())
+// buildProgram builds every top-level item. With partial set, a broken
+// program (or an ERROR node standing in for it) is still walked, items
+// with syntax errors are skipped and build errors are collected.
+func buildProgram(n *sitter.Node, src []byte, partial bool, log *logger) (*Program, []error) {
+	if n.Kind() != "program" && !(partial && n.IsError()) {
+		return nil, []error{fmt.Errorf("expected program node, got %s", n.Kind())}
 	}
 
 	p := &Program{}
+	p.Symbols.Push()
+	var errs []error
 
 	for i := uint(0); i < n.NamedChildCount(); i++ {
 		c := n.NamedChild(i)
 		if c == nil {
 			continue
 		}
+		if partial && c.HasError() {
+			continue
+		}
 		switch c.Kind() {
 		case "declaration_statement":
 			decl, err := buildVarDecl(c, src)
 			if err != nil {
-				return nil, err
+				errs = append(errs, err)
+				if !partial {
+					return nil, errs
+				}
+				continue
 			}
 			p.Declarations = append(p.Declarations, decl)
+			p.Symbols.Insert(decl.Name, varSymbol(decl.Type, lineOf(decl)))
+			log.Debugf("global %s %s", decl.Type.Kind, decl.Name)
 		case "method_declaration_statement":
 			m, err := buildMethodDecl(c, src)
 			if err != nil {
-				return nil, err
+				errs = append(errs, err)
+				if !partial {
+					return nil, errs
+				}
+				continue
 			}
 			p.Methods = append(p.Methods, m)
+			p.Symbols.Insert(m.Name, methodSymbol(m))
+			kind := "method"
+			if m.Extern {
+				kind = "extern method"
+			}
+			log.Debugf("%s %s %s with %d params", kind, m.Return.Kind, m.Name, len(m.Params))
 		}
 	}
 
-	return p, nil
+	return p, errs
 }
 
 func buildVarDecl(n *sitter.Node, src []byte) (*VarDecl, error) {
@@ -78,7 +153,7 @@ func buildVarDecl(n *sitter.Node, src []byte) (*VarDecl, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &VarDecl{Type: t, Name: name, Value: val}, nil
+	return &VarDecl{NodeBase: at(n), Type: t, Name: name, Value: val}, nil
 }
 
 func buildType(n *sitter.Node, src []byte) (*TypeNode, error) {
@@ -92,6 +167,18 @@ func buildType(n *sitter.Node, src []byte) (*TypeNode, error) {
 		return &TypeNode{Kind: TypeBool}, nil
 	case "integer":
 		return &TypeNode{Kind: TypeInteger}, nil
+	case "int8":
+		return &TypeNode{Kind: TypeInt8}, nil
+	case "int16":
+		return &TypeNode{Kind: TypeInt16}, nil
+	case "int32":
+		return &TypeNode{Kind: TypeInt32}, nil
+	case "int64":
+		return &TypeNode{Kind: TypeInt64}, nil
+	case "string":
+		return &TypeNode{Kind: TypeString}, nil
+	case "char":
+		return &TypeNode{Kind: TypeChar}, nil
 	default:
 		return nil, fmt.Errorf("unknown type node: %s", n.Kind())
 	}
@@ -109,6 +196,7 @@ func buildMethodDecl(n *sitter.Node, src []byte) (*MethodDecl, error) {
 
 	// parameters
 	var params []*Parameter
+	seen := map[Identifier]bool{}
 	for i := uint(0); i < n.NamedChildCount(); i++ {
 		c := n.NamedChild(i)
 		if c.Kind() == "parameter" {
@@ -116,6 +204,10 @@ func buildMethodDecl(n *sitter.Node, src []byte) (*MethodDecl, error) {
 			if err != nil {
 				return nil, err
 			}
+			if seen[p.Name] {
+				return nil, fmt.Errorf("line %d: duplicate parameter %s in method %s", line(c), p.Name, name)
+			}
+			seen[p.Name] = true
 			params = append(params, p)
 		}
 	}
@@ -138,11 +230,12 @@ func buildMethodDecl(n *sitter.Node, src []byte) (*MethodDecl, error) {
 	}
 
 	return &MethodDecl{
-		Return: t,
-		Name:   name,
-		Params: params,
-		Body:   body,
-		Extern: extern,
+		NodeBase: at(n),
+		Return:   t,
+		Name:     name,
+		Params:   params,
+		Body:     body,
+		Extern:   extern,
 	}, nil
 }
 
@@ -154,7 +247,7 @@ func buildParameter(n *sitter.Node, src []byte) (*Parameter, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Parameter{Type: t, Name: Identifier(text(idNode, src))}, nil
+	return &Parameter{NodeBase: at(n), Type: t, Name: Identifier(text(idNode, src))}, nil
 }
 
 // ----------------------------------------------------------------------
@@ -162,7 +255,10 @@ func buildParameter(n *sitter.Node, src []byte) (*Parameter, error) {
 // ----------------------------------------------------------------------
 
 func buildBlock(n *sitter.Node, src []byte) (*Block, error) {
-	b := &Block{}
+	if n == nil {
+		return nil, fmt.Errorf("nil block node")
+	}
+	b := &Block{NodeBase: at(n)}
 	for i := uint(0); i < n.NamedChildCount(); i++ {
 		c := n.NamedChild(i)
 		switch c.Kind() {
@@ -196,12 +292,18 @@ func buildBlock(n *sitter.Node, src []byte) (*Block, error) {
 				return nil, err
 			}
 			b.Stmts = append(b.Stmts, ws)
+		case "for_statement":
+			fs, err := buildForStmt(c, src)
+			if err != nil {
+				return nil, err
+			}
+			b.Stmts = append(b.Stmts, fs)
 		case "method_call":
 			e, err := buildExpr(c, src)
 			if err != nil {
 				return nil, err
 			}
-			b.Stmts = append(b.Stmts, &ExprStmt{Expr: e})
+			b.Stmts = append(b.Stmts, &ExprStmt{NodeBase: at(c), Expr: e})
 		}
 	}
 	return b, nil
@@ -214,19 +316,19 @@ func buildAssignment(n *sitter.Node, src []byte) (*Assignment, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Assignment{Target: Identifier(text(idNode, src)), Value: val}, nil
+	return &Assignment{NodeBase: at(n), Target: Identifier(text(idNode, src)), Value: val}, nil
 }
 
 func buildReturnStmt(n *sitter.Node, src []byte) (*ReturnStmt, error) {
 	valNode := n.ChildByFieldName("value")
 	if valNode == nil {
-		return &ReturnStmt{}, nil
+		return &ReturnStmt{NodeBase: at(n)}, nil
 	}
 	val, err := buildExpr(valNode, src)
 	if err != nil {
 		return nil, err
 	}
-	return &ReturnStmt{Value: val}, nil
+	return &ReturnStmt{NodeBase: at(n), Value: val}, nil
 }
 
 func buildIfStmt(n *sitter.Node, src []byte) (*IfStmt, error) {
@@ -235,6 +337,11 @@ func buildIfStmt(n *sitter.Node, src []byte) (*IfStmt, error) {
 		// fallback: in your grammar it's field-less, just the first child
 		condNode = n.NamedChild(0)
 	}
+	// On a malformed if the first child can be absent, the then-block, an
+	// ERROR node or a MISSING placeholder; none of those is a condition.
+	if condNode == nil || condNode.IsMissing() || !isExprKind(condNode.Kind()) {
+		return nil, fmt.Errorf("line %d: if statement is missing its condition", line(n))
+	}
 	cond, err := buildExpr(condNode, src)
 	if err != nil {
 		return nil, err
@@ -249,27 +356,70 @@ func buildIfStmt(n *sitter.Node, src []byte) (*IfStmt, error) {
 		}
 	}
 	if len(blocks) > 0 {
-		thenBlk, _ = buildBlock(blocks[0], src)
+		thenBlk, err = buildBlock(blocks[0], src)
+		if err != nil {
+			return nil, err
+		}
 	}
 	if len(blocks) > 1 {
-		elseBlk, _ = buildBlock(blocks[1], src)
+		elseBlk, err = buildBlock(blocks[1], src)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return &IfStmt{Cond: cond, Then: thenBlk, Else: elseBlk}, nil
+	return &IfStmt{NodeBase: at(n), Cond: cond, Then: thenBlk, Else: elseBlk}, nil
 }
 
 func buildWhileStmt(n *sitter.Node, src []byte) (*WhileStmt, error) {
+	// while_statement has no fields: the condition is the first named child
+	// and the body the last one. Error recovery can leave either of them out.
+	count := n.NamedChildCount()
 	condNode := n.NamedChild(0)
+	if condNode == nil || condNode.IsMissing() || !isExprKind(condNode.Kind()) {
+		return nil, fmt.Errorf("line %d: while statement is missing its condition", line(n))
+	}
+	bodyNode := n.NamedChild(count - 1)
+	if count < 2 || bodyNode.Kind() != "block" {
+		return nil, fmt.Errorf("line %d: while statement is missing its body", line(n))
+	}
+
 	cond, err := buildExpr(condNode, src)
 	if err != nil {
 		return nil, err
 	}
-	bodyNode := n.NamedChild(n.NamedChildCount() - 1)
 	body, err := buildBlock(bodyNode, src)
 	if err != nil {
 		return nil, err
 	}
-	return &WhileStmt{Cond: cond, Body: body}, nil
+	return &WhileStmt{NodeBase: at(n), Cond: cond, Body: body}, nil
+}
+
+func buildForStmt(n *sitter.Node, src []byte) (*ForStmt, error) {
+	t, err := buildType(n.ChildByFieldName("type"), src)
+	if err != nil {
+		return nil, err
+	}
+	init, err := buildExpr(n.ChildByFieldName("init"), src)
+	if err != nil {
+		return nil, err
+	}
+	bound, err := buildExpr(n.ChildByFieldName("bound"), src)
+	if err != nil {
+		return nil, err
+	}
+	body, err := buildBlock(n.ChildByFieldName("body"), src)
+	if err != nil {
+		return nil, err
+	}
+
+	name := Identifier(text(n.ChildByFieldName("identifier"), src))
+	return &ForStmt{
+		NodeBase: at(n),
+		Var:      &VarDecl{NodeBase: at(n), Type: t, Name: name, Value: init},
+		Bound:    bound,
+		Body:     body,
+	}, nil
 }
 
 // ----------------------------------------------------------------------
@@ -285,28 +435,110 @@ func buildExpr(n *sitter.Node, src []byte) (Expr, error) {
 		// parse int
 		var v int
 		fmt.Sscanf(text(n, src), "%d", &v)
-		return &IntLiteral{Value: v, Type: TypeInteger}, nil
+		return &IntLiteral{NodeBase: at(n), Value: v, Type: TypeInteger}, nil
+	case "float":
+		return nil, fmt.Errorf("line %d: floating-point literals are not supported", line(n))
+	case "string_literal":
+		if lit := text(n, src); n.IsMissing() || len(lit) < 2 {
+			return nil, fmt.Errorf("line %d: unterminated string literal", line(n))
+		}
+		v, err := unquoteString(text(n, src))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line(n), err)
+		}
+		return &StringLiteral{NodeBase: at(n), Value: v, Type: TypeString}, nil
+	case "char_literal":
+		if lit := text(n, src); n.IsMissing() || len(lit) < 3 {
+			return nil, fmt.Errorf("line %d: unterminated character literal", line(n))
+		}
+		v, err := unquoteChar(text(n, src))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line(n), err)
+		}
+		return &CharLiteral{NodeBase: at(n), Value: v, Type: TypeChar}, nil
 	case "true":
-		return &BoolLiteral{Value: true, Type: TypeBool}, nil
+		return &BoolLiteral{NodeBase: at(n), Value: true, Type: TypeBool}, nil
 	case "false":
-		return &BoolLiteral{Value: false, Type: TypeBool}, nil
+		return &BoolLiteral{NodeBase: at(n), Value: false, Type: TypeBool}, nil
 	case "identifier":
-		return &IdentExpr{Name: Identifier(text(n, src))}, nil
+		return &IdentExpr{NodeBase: at(n), Name: Identifier(text(n, src))}, nil
 	case "method_call":
 		return buildCallExpr(n, src)
-	case "int_sum", "int_sub", "int_prod", "int_div",
-		"rel_eq", "rel_lt", "rel_gt",
+	case "int_sum", "int_sub", "int_prod", "int_div", "int_mod",
+		"rel_eq", "rel_neq", "rel_lt", "rel_gt",
 		"bool_conjunction", "bool_disjunction":
 		return buildBinaryExpr(n, src)
 	case "unary_expression": // if you decide to name it so
 		return buildUnaryExpr(n, src)
 	case "(": // parenthesized
-		inner := n.NamedChild(0)
-		return &ParenExpr{Inner: mustExpr(inner, src)}, nil
+		inner, err := buildExpr(n.NamedChild(0), src)
+		if err != nil {
+			return nil, err
+		}
+		return &ParenExpr{NodeBase: at(n), Inner: inner}, nil
 	}
 	return nil, fmt.Errorf("unhandled expression node type: %s", n.Kind())
 }
 
+// unquoteString strips the quotes from a string_literal token and decodes
+// its escape sequences: \n, \t, \0, \\, \" and \'.
+func unquoteString(lit string) (string, error) {
+	body := lit[1 : len(lit)-1]
+	var sb strings.Builder
+	for i := 0; i < len(body); i++ {
+		if body[i] != '\\' {
+			sb.WriteByte(body[i])
+			continue
+		}
+		i++ // the grammar guarantees a character after the backslash
+		c, ok := escapes[body[i]]
+		if !ok {
+			return "", fmt.Errorf("unknown escape sequence \\%c in string literal", body[i])
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String(), nil
+}
+
+// unquoteChar decodes a char_literal token: one character, or one of the
+// escapes unquoteString accepts.
+func unquoteChar(lit string) (rune, error) {
+	body := lit[1 : len(lit)-1]
+	if body[0] != '\\' {
+		r, _ := utf8.DecodeRuneInString(body)
+		return r, nil
+	}
+	c, ok := escapes[body[1]]
+	if !ok {
+		return 0, fmt.Errorf("unknown escape sequence \\%c in character literal", body[1])
+	}
+	return rune(c), nil
+}
+
+// escapes maps the character after a backslash to the byte it stands for.
+var escapes = map[byte]byte{
+	'n':  '\n',
+	't':  '\t',
+	'0':  0,
+	'\\': '\\',
+	'"':  '"',
+	'\'': '\'',
+}
+
+// isExprKind reports whether kind is an expression node kind the grammar
+// produces and buildExpr can build. The grammar's unary - and ! have no
+// node of their own, so they are not among them.
+func isExprKind(kind string) bool {
+	switch kind {
+	case "num", "float", "string_literal", "char_literal", "true", "false", "identifier", "method_call",
+		"int_sum", "int_sub", "int_prod", "int_div", "int_mod",
+		"rel_eq", "rel_neq", "rel_lt", "rel_gt",
+		"bool_conjunction", "bool_disjunction":
+		return true
+	}
+	return false
+}
+
 func buildCallExpr(n *sitter.Node, src []byte) (Expr, error) {
 	idNode := n.Child(0)
 	args := []Expr{}
@@ -321,7 +553,7 @@ func buildCallExpr(n *sitter.Node, src []byte) (Expr, error) {
 		}
 		args = append(args, e)
 	}
-	return &CallExpr{Callee: Identifier(text(idNode, src)), Args: args}, nil
+	return &CallExpr{NodeBase: at(n), Callee: Identifier(text(idNode, src)), Args: args}, nil
 }
 
 func buildBinaryExpr(n *sitter.Node, src []byte) (Expr, error) {
@@ -351,9 +583,15 @@ func buildBinaryExpr(n *sitter.Node, src []byte) (Expr, error) {
 	case "int_div":
 		op = BinDiv
 		t = TypeInteger
+	case "int_mod":
+		op = BinMod
+		t = TypeInteger
 	case "rel_eq":
 		op = BinEq
 		t = TypeBool
+	case "rel_neq":
+		op = BinNeq
+		t = TypeBool
 	case "rel_lt":
 		op = BinLT
 		t = TypeBool
@@ -367,7 +605,7 @@ func buildBinaryExpr(n *sitter.Node, src []byte) (Expr, error) {
 		op = BinOr
 		t = TypeBool
 	}
-	return &BinaryExpr{Left: l, Op: op, Right: r, Type: t}, nil
+	return &BinaryExpr{NodeBase: at(n), Left: l, Op: op, Right: r, Type: t}, nil
 }
 
 func buildUnaryExpr(n *sitter.Node, src []byte) (Expr, error) {
@@ -390,10 +628,5 @@ func buildUnaryExpr(n *sitter.Node, src []byte) (Expr, error) {
 	default:
 		return nil, fmt.Errorf("unknown unary op: %s", text(opNode, src))
 	}
-	return &UnaryExpr{Op: op, Expr: expr, Type: t}, nil
-}
-
-func mustExpr(n *sitter.Node, src []byte) Expr {
-	e, _ := buildExpr(n, src)
-	return e
+	return &UnaryExpr{NodeBase: at(n), Op: op, Expr: expr, Type: t}, nil
 }
@@ -0,0 +1,582 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// parseSource parses src with the project grammar; the tree is closed
+// when the test ends.
+func parseSource(t testing.TB, src string) *sitter.Node {
+	t.Helper()
+	parser, err := newParser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(parser.Close)
+	tree := parser.Parse([]byte(src), nil)
+	t.Cleanup(tree.Close)
+	return tree.RootNode()
+}
+
+// parseExpr builds the AST of a single expression by wrapping it as the
+// value of a return statement in a minimal program.
+func parseExpr(src string) (Expr, error) {
+	parser, err := newParser()
+	if err != nil {
+		return nil, err
+	}
+	defer parser.Close()
+
+	code := []byte("program { void main() { return " + src + "; } }")
+	tree := parser.Parse(code, nil)
+	defer tree.Close()
+	root := tree.RootNode()
+	if root.HasError() {
+		return nil, fmt.Errorf("syntax error in expression %q", src)
+	}
+
+	p, err := BuildAST(root, code)
+	if err != nil {
+		return nil, err
+	}
+	return p.Methods[0].Body.Stmts[0].(*ReturnStmt).Value, nil
+}
+
+func TestBuildASTPartialKeepsValidMethods(t *testing.T) {
+	src := `program {
+	integer broken() {
+		return 1 + ;
+	}
+
+	integer ok() {
+		return 2;
+	}
+}`
+	root := parseSource(t, src)
+	if !root.HasError() {
+		t.Fatal("expected the source to contain a syntax error")
+	}
+	if errs := syntaxErrors(root, []byte(src)); len(errs) == 0 {
+		t.Error("expected syntaxErrors to report the broken method")
+	}
+
+	p, errs := BuildASTPartial(root, []byte(src))
+	if len(errs) != 0 {
+		t.Fatalf("unexpected build errors: %v", errs)
+	}
+	if len(p.Methods) != 1 || p.Methods[0].Name != "ok" {
+		t.Fatalf("expected only method ok to be built, got %v", p)
+	}
+	ret, ok := p.Methods[0].Body.Stmts[0].(*ReturnStmt)
+	if !ok {
+		t.Fatalf("expected a return statement, got %T", p.Methods[0].Body.Stmts[0])
+	}
+	if lit, ok := ret.Value.(*IntLiteral); !ok || lit.Value != 2 {
+		t.Errorf("expected return 2, got %#v", ret.Value)
+	}
+}
+
+func TestBuildSizedIntegerDeclarations(t *testing.T) {
+	src := `program {
+	int8 a = 1;
+	int16 b = 2;
+	int32 c = 3;
+	int64 d = 4;
+	integer e = 5;
+}`
+	p, err := BuildAST(parseSource(t, src), []byte(src))
+	if err != nil {
+		t.Fatalf("BuildAST: %v", err)
+	}
+
+	want := []struct {
+		kind TypeKind
+		size int
+	}{
+		{TypeInt8, 1},
+		{TypeInt16, 2},
+		{TypeInt32, 4},
+		{TypeInt64, 8},
+		{TypeInteger, 8},
+	}
+	if len(p.Declarations) != len(want) {
+		t.Fatalf("expected %d declarations, got %d", len(want), len(p.Declarations))
+	}
+	for i, w := range want {
+		d := p.Declarations[i]
+		if d.Type.Kind != w.kind {
+			t.Errorf("%s: expected type %s, got %s", d.Name, w.kind, d.Type.Kind)
+		}
+		if got := d.Type.Kind.Size(); got != w.size {
+			t.Errorf("%s: expected slot size %d, got %d", d.Name, w.size, got)
+		}
+	}
+}
+
+// FuzzBuildAST checks that the builder reports malformed trees as errors
+// instead of panicking, whatever tree-sitter recovered from the input.
+func FuzzBuildAST(f *testing.F) {
+	seeds := []string{
+		"",
+		"program { }",
+		"program { integer x = 1; }",
+		"program { void main() { while (true) } }",
+		"program { void main() { if () then { } } }",
+		"program { integer f(integer a, bool b) extern; }",
+		"program { void main() { x = f(1, (2 + 3) * -4); return; } }",
+		"program { bool g() { if (a < b && !c) then { return true; } else { return false; } } }",
+		"program { void main( { integer = ; } ",
+		"program { void main() { string s = \"abc; } }",
+		"program { void main() { char c = 'a; } }",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	paths, _ := filepath.Glob(filepath.Join("target_source", "*.ctds"))
+	for _, path := range paths {
+		if src, err := os.ReadFile(path); err == nil {
+			f.Add(string(src))
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		root := parseSource(t, src)
+		BuildAST(root, []byte(src))
+		BuildASTPartial(root, []byte(src))
+		syntaxErrors(root, []byte(src))
+	})
+}
+
+// findKind returns the first node of the given kind under n, depth first.
+func findKind(n *sitter.Node, kind string) *sitter.Node {
+	if n == nil {
+		return nil
+	}
+	if n.Kind() == kind {
+		return n
+	}
+	for i := uint(0); i < n.ChildCount(); i++ {
+		if found := findKind(n.Child(i), kind); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func TestBuildWhileStmtRecovered(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			"missing condition",
+			"program {\n\tvoid f() {\n\t\twhile () { return; }\n\t}\n}",
+			"line 3: while statement is missing its condition",
+		},
+		{
+			"missing body",
+			"program {\n\tvoid f() {\n\t\twhile (true)\n\t}\n}",
+			"line 3: while statement is missing its body",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			whileNode := findKind(parseSource(t, tt.src), "while_statement")
+			if whileNode == nil {
+				t.Fatal("no while_statement in the parsed tree")
+			}
+
+			_, err := buildWhileStmt(whileNode, []byte(tt.src))
+			if err == nil {
+				t.Fatal("expected an error for a malformed while statement")
+			}
+			if err.Error() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestBuildIfStmtMissingCondition(t *testing.T) {
+	src := "program {\n\tvoid f() {\n\t\tif () then { return; }\n\t}\n}"
+	ifNode := findKind(parseSource(t, src), "if_statement")
+	if ifNode == nil {
+		t.Fatal("no if_statement in the parsed tree")
+	}
+
+	_, err := buildIfStmt(ifNode, []byte(src))
+	if err == nil {
+		t.Fatal("expected an error for an if node without a condition")
+	}
+	if want := "line 3: if statement is missing its condition"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestParseExprPrecedence(t *testing.T) {
+	e, err := parseExpr("1 + 2 * x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, ok := e.(*BinaryExpr)
+	if !ok || sum.Op != BinAdd {
+		t.Fatalf("expected + at the root, got %#v", e)
+	}
+	if lit, ok := sum.Left.(*IntLiteral); !ok || lit.Value != 1 {
+		t.Errorf("expected left operand 1, got %#v", sum.Left)
+	}
+	prod, ok := sum.Right.(*BinaryExpr)
+	if !ok || prod.Op != BinMul {
+		t.Fatalf("expected * to bind tighter than +, got %#v", sum.Right)
+	}
+	if id, ok := prod.Right.(*IdentExpr); !ok || id.Name != "x" {
+		t.Errorf("expected right operand x, got %#v", prod.Right)
+	}
+}
+
+func TestParseExprTypes(t *testing.T) {
+	tests := []struct {
+		src  string
+		want TypeKind
+	}{
+		{"7", TypeInteger},
+		{"false", TypeBool},
+		{"a < b", TypeBool},
+		{"a - b / 2", TypeInteger},
+		{"a || b && c", TypeBool},
+	}
+	for _, tt := range tests {
+		e, err := parseExpr(tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		var got TypeKind
+		switch e := e.(type) {
+		case *IntLiteral:
+			got = e.Type
+		case *BoolLiteral:
+			got = e.Type
+		case *BinaryExpr:
+			got = e.Type
+		default:
+			t.Errorf("%s: unexpected node %T", tt.src, e)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected type %s, got %s", tt.src, tt.want, got)
+		}
+	}
+}
+
+func TestParseExprCall(t *testing.T) {
+	e, err := parseExpr("f(1, g(y))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, ok := e.(*CallExpr)
+	if !ok || call.Callee != "f" || len(call.Args) != 2 {
+		t.Fatalf("expected f with two arguments, got %#v", e)
+	}
+	if inner, ok := call.Args[1].(*CallExpr); !ok || inner.Callee != "g" || len(inner.Args) != 1 {
+		t.Errorf("expected nested call g(y), got %#v", call.Args[1])
+	}
+}
+
+func TestBuildMethodDuplicateParameter(t *testing.T) {
+	src := `program {
+	integer f(integer a,
+	          bool a) {
+		return 1;
+	}
+}`
+	p, errs := BuildASTPartial(parseSource(t, src), []byte(src))
+	if len(errs) != 1 {
+		t.Fatalf("expected exactly one error, got %v", errs)
+	}
+	if want := "line 3: duplicate parameter a in method f"; errs[0].Error() != want {
+		t.Errorf("expected %q, got %q", want, errs[0].Error())
+	}
+	if len(p.Methods) != 0 {
+		t.Errorf("method with duplicate parameters should not be built")
+	}
+}
+
+func TestBuildExternRecordsParameters(t *testing.T) {
+	src := `program {
+	integer foo(integer a, bool b) extern;
+}`
+	p, err := BuildAST(parseSource(t, src), []byte(src))
+	if err != nil {
+		t.Fatalf("BuildAST: %v", err)
+	}
+	if len(p.Methods) != 1 {
+		t.Fatalf("expected one method, got %d", len(p.Methods))
+	}
+	m := p.Methods[0]
+	if !m.Extern || m.Body != nil {
+		t.Errorf("expected an extern method without body, got extern=%v body=%v", m.Extern, m.Body)
+	}
+	if m.Return.Kind != TypeInteger {
+		t.Errorf("expected integer return type, got %s", m.Return.Kind)
+	}
+	if len(m.Params) != 2 ||
+		m.Params[0].Name != "a" || m.Params[0].Type.Kind != TypeInteger ||
+		m.Params[1].Name != "b" || m.Params[1].Type.Kind != TypeBool {
+		t.Errorf("expected params (integer a, bool b), got %v", m.Params)
+	}
+}
+
+func TestParseExprModulo(t *testing.T) {
+	e, err := parseExpr("a + b % 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, ok := e.(*BinaryExpr)
+	if !ok || sum.Op != BinAdd {
+		t.Fatalf("expected + at the root, got %#v", e)
+	}
+	mod, ok := sum.Right.(*BinaryExpr)
+	if !ok || mod.Op != BinMod {
+		t.Fatalf("expected %% to bind like * and /, got %#v", sum.Right)
+	}
+	if mod.Type != TypeInteger {
+		t.Errorf("expected %% to yield integer, got %s", mod.Type)
+	}
+}
+
+func TestBuildRejectsFloatLiteral(t *testing.T) {
+	src := `program {
+	integer f() {
+		return 3.14;
+	}
+}`
+	_, err := BuildAST(parseSource(t, src), []byte(src))
+	if err == nil {
+		t.Fatal("expected an error for a floating-point literal")
+	}
+	if want := "line 3: floating-point literals are not supported"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestParseExprNotEqual(t *testing.T) {
+	for _, src := range []string{"x != 3", "true != flag"} {
+		e, err := parseExpr(src)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		neq, ok := e.(*BinaryExpr)
+		if !ok || neq.Op != BinNeq {
+			t.Errorf("%s: expected a != expression, got %#v", src, e)
+			continue
+		}
+		if neq.Type != TypeBool {
+			t.Errorf("%s: expected bool result, got %s", src, neq.Type)
+		}
+	}
+}
+
+func TestBuildForStmt(t *testing.T) {
+	src := `program {
+	integer sum(integer n) {
+		integer acc = 0;
+		for (integer i = 1 to n + 1) {
+			acc = acc + i;
+		}
+		return acc;
+	}
+}`
+	p, err := BuildAST(parseSource(t, src), []byte(src))
+	if err != nil {
+		t.Fatalf("BuildAST: %v", err)
+	}
+	body := p.Methods[0].Body
+	if len(body.Stmts) != 2 {
+		t.Fatalf("expected for + return, got %d statements", len(body.Stmts))
+	}
+	loop, ok := body.Stmts[0].(*ForStmt)
+	if !ok {
+		t.Fatalf("expected a ForStmt, got %T", body.Stmts[0])
+	}
+	if loop.Var.Name != "i" || loop.Var.Type.Kind != TypeInteger {
+		t.Errorf("expected loop variable integer i, got %s %s", loop.Var.Type.Kind, loop.Var.Name)
+	}
+	if lit, ok := loop.Var.Value.(*IntLiteral); !ok || lit.Value != 1 {
+		t.Errorf("expected init 1, got %#v", loop.Var.Value)
+	}
+	if bound, ok := loop.Bound.(*BinaryExpr); !ok || bound.Op != BinAdd {
+		t.Errorf("expected bound n + 1, got %#v", loop.Bound)
+	}
+	if len(loop.Body.Stmts) != 1 {
+		t.Errorf("expected one statement in the loop body, got %d", len(loop.Body.Stmts))
+	}
+}
+
+func TestUnquoteString(t *testing.T) {
+	tests := []struct {
+		lit  string
+		want string
+	}{
+		{`""`, ""},
+		{`"hello"`, "hello"},
+		{`"line\n"`, "line\n"},
+		{`"tab\there"`, "tab\there"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"it\'s"`, "it's"},
+	}
+	for _, tt := range tests {
+		got, err := unquoteString(tt.lit)
+		if err != nil {
+			t.Errorf("%s: %v", tt.lit, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.lit, tt.want, got)
+		}
+	}
+
+	if _, err := unquoteString(`"bad \q"`); err == nil || !strings.Contains(err.Error(), `\q`) {
+		t.Errorf("expected an unknown escape error, got %v", err)
+	}
+}
+
+func TestBuildStringDeclaration(t *testing.T) {
+	src := `program {
+	string greeting = "hi\n";
+
+	void main() {
+		print("done");
+	}
+}`
+	p, err := BuildAST(parseSource(t, src), []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := p.Declarations[0]
+	if d.Type.Kind != TypeString {
+		t.Errorf("expected a string declaration, got %s", d.Type.Kind)
+	}
+	if lit, ok := d.Value.(*StringLiteral); !ok || lit.Value != "hi\n" {
+		t.Errorf("expected the decoded literal \"hi\\n\", got %#v", d.Value)
+	}
+	call := p.Methods[0].Body.Stmts[0].(*ExprStmt).Expr.(*CallExpr)
+	if lit, ok := call.Args[0].(*StringLiteral); !ok || lit.Value != "done" {
+		t.Errorf("expected a string argument, got %#v", call.Args[0])
+	}
+}
+
+func TestUnquoteChar(t *testing.T) {
+	tests := []struct {
+		lit  string
+		want rune
+	}{
+		{`'a'`, 'a'},
+		{`'ñ'`, 'ñ'},
+		{`'\n'`, '\n'},
+		{`'\''`, '\''},
+		{`'\\'`, '\\'},
+		{`'\0'`, 0},
+	}
+	for _, tt := range tests {
+		got, err := unquoteChar(tt.lit)
+		if err != nil {
+			t.Errorf("%s: %v", tt.lit, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.lit, tt.want, got)
+		}
+	}
+
+	if _, err := unquoteChar(`'\q'`); err == nil || !strings.Contains(err.Error(), `\q`) {
+		t.Errorf("expected an unknown escape error, got %v", err)
+	}
+}
+
+func TestParseExprChar(t *testing.T) {
+	e, err := parseExpr(`c == '\n'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin, ok := e.(*BinaryExpr)
+	if !ok || bin.Op != BinEq {
+		t.Fatalf("expected an == comparison, got %#v", e)
+	}
+	lit, ok := bin.Right.(*CharLiteral)
+	if !ok || lit.Value != '\n' || lit.Type != TypeChar {
+		t.Errorf("expected the char literal '\\n', got %#v", bin.Right)
+	}
+}
+
+func TestBuildRecordsMethodSignatures(t *testing.T) {
+	src := `program {
+	integer sum(integer a, bool b) {
+		return a;
+	}
+}`
+	p, err := BuildAST(parseSource(t, src), []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sym, ok := p.Symbols.Lookup("sum")
+	if !ok || sym.Func == nil {
+		t.Fatalf("expected sum in p.Symbols with a signature, got %+v", sym)
+	}
+	f := sym.Func
+	if f.Return != TypeInteger || f.Arity != 2 || len(f.Params) != 2 {
+		t.Fatalf("unexpected signature %+v", f)
+	}
+	if f.Params[0].Name != "a" || f.Params[1].Type.Kind != TypeBool {
+		t.Errorf("unexpected parameters %v, %v", f.Params[0], f.Params[1])
+	}
+}
+
+func TestBuildProgramSymbols(t *testing.T) {
+	src := `program {
+	integer x = 1;
+	bool done = false;
+
+	integer get_int() extern;
+
+	void main() {
+		x = get_int();
+	}
+}`
+	p, err := BuildAST(parseSource(t, src), []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Symbols) != 1 {
+		t.Fatalf("expected a single top-level scope, got %d", len(p.Symbols))
+	}
+
+	for _, v := range []struct {
+		name Identifier
+		typ  TypeKind
+		line int
+	}{{"x", TypeInteger, 2}, {"done", TypeBool, 3}} {
+		sym, ok := p.Symbols.Lookup(v.name)
+		if !ok || !sym.isVar || sym.Type != v.typ || sym.Line != v.line {
+			t.Errorf("%s: expected a %s variable from line %d, got %+v", v.name, v.typ, v.line, sym)
+		}
+	}
+	for _, m := range []struct {
+		name Identifier
+		ret  TypeKind
+	}{{"get_int", TypeInteger}, {"main", TypeVoid}} {
+		sym, ok := p.Symbols.Lookup(m.name)
+		if !ok || sym.isVar || sym.Func == nil || sym.Func.Return != m.ret || sym.Func.Arity != 0 {
+			t.Errorf("%s: expected a method returning %s with no parameters, got %+v", m.name, m.ret, sym)
+		}
+	}
+}
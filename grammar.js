@@ -47,7 +47,17 @@ export default grammar({
     _void_type: (_$) => "void",
     _bool_type: (_$) => "bool",
     _int_type: (_$) => "integer",
-    _type: ($) => choice($._int_type, $._bool_type),
+    _sized_int_type: (_$) => choice("int8", "int16", "int32", "int64"),
+    _string_type: (_$) => "string",
+    _char_type: (_$) => "char",
+    _type: ($) =>
+      choice(
+        $._int_type,
+        $._sized_int_type,
+        $._bool_type,
+        $._string_type,
+        $._char_type
+      ),
 
     // ────────────────────────────────────────────────────────────────────────────
     // Blocks & statements
@@ -69,11 +79,26 @@ export default grammar({
         seq($.method_call, ";"),
         seq($.return_statement, ";"),
         $.if_statement,
-        $.while_statement
+        $.while_statement,
+        $.for_statement
       ),
 
     while_statement: ($) => seq("while", "(", $._expression, ")", $.block),
 
+    for_statement: ($) =>
+      seq(
+        "for",
+        "(",
+        field("type", $._type),
+        field("identifier", $.identifier),
+        "=",
+        field("init", $._expression),
+        "to",
+        field("bound", $._expression),
+        ")",
+        field("body", $.block)
+      ),
+
     if_statement: ($) =>
       seq(
         "if",
@@ -127,6 +152,9 @@ export default grammar({
           $._rel_operation,
           $._bool_operation,
           $.num,
+          $.float,
+          $.string_literal,
+          $.char_literal,
           $._bool_const,
           $.identifier,
           $.method_call,
@@ -135,13 +163,18 @@ export default grammar({
         )
       ),
 
-    _rel_operation: ($) => choice($.rel_gt, $.rel_lt, $.rel_eq),
+    _rel_operation: ($) => choice($.rel_gt, $.rel_lt, $.rel_eq, $.rel_neq),
 
     rel_eq: ($) =>
       prec.left(
         seq(field("left", $._expression), "==", field("right", $._expression))
       ),
 
+    rel_neq: ($) =>
+      prec.left(
+        seq(field("left", $._expression), "!=", field("right", $._expression))
+      ),
+
     rel_lt: ($) =>
       prec.left(
         seq(field("left", $._expression), "<", field("right", $._expression))
@@ -164,7 +197,8 @@ export default grammar({
         seq(field("left", $._expression), "||", field("right", $._expression))
       ),
 
-    _int_operation: ($) => choice($.int_prod, $.int_div, $.int_sum, $.int_sub),
+    _int_operation: ($) =>
+      choice($.int_prod, $.int_div, $.int_mod, $.int_sum, $.int_sub),
 
     int_prod: ($) =>
       prec.left(
@@ -176,6 +210,11 @@ export default grammar({
         1,
         seq(field("left", $._expression), "/", field("right", $._expression))
       ),
+    int_mod: ($) =>
+      prec.left(
+        1,
+        seq(field("left", $._expression), "%", field("right", $._expression))
+      ),
     int_sum: ($) =>
       prec.left(
         seq(field("left", $._expression), "+", field("right", $._expression))
@@ -196,6 +235,18 @@ export default grammar({
 
     num: (_$) => /\d+/,
 
+    // Not a supported type yet: parsed only so the builder can reject it
+    // with a clear message instead of a generic syntax error.
+    float: (_$) => /\d+\.\d+/,
+
+    // Double-quoted, on one line; a backslash escapes the next character
+    // (the builder decides which escapes are valid).
+    string_literal: (_$) =>
+      token(seq('"', repeat(choice(/[^"\\\n]/, /\\./)), '"')),
+
+    // A single character or escape between single quotes.
+    char_literal: (_$) => token(seq("'", choice(/[^'\\\n]/, /\\./), "'")),
+
     comment: ($) =>
       token(
         choice(seq("//", /.*/), seq("/*", /[^*]*\*+([^/*][^*]*\*+)*/, "/"))
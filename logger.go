@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// logLevel controls how much the compiler reports about its own work.
+type logLevel int
+
+const (
+	logQuiet logLevel = iota // default: only errors and results
+	logInfo                  // -v: phase entry/exit
+	logDebug                 // -vv: individual decisions inside each phase
+)
+
+func (l logLevel) String() string {
+	switch l {
+	case logInfo:
+		return "info"
+	case logDebug:
+		return "debug"
+	default:
+		return "quiet"
+	}
+}
+
+// logger writes leveled progress messages, usually to stderr.
+type logger struct {
+	w     io.Writer
+	level logLevel
+}
+
+func newLogger(w io.Writer, level logLevel) *logger {
+	return &logger{w: w, level: level}
+}
+
+func (l *logger) logf(level logLevel, format string, args ...any) {
+	if l == nil || l.level < level {
+		return
+	}
+	fmt.Fprintf(l.w, "["+level.String()+"] "+format+"\n", args...)
+}
+
+// Infof logs phase-level progress (shown with -v).
+func (l *logger) Infof(format string, args ...any) { l.logf(logInfo, format, args...) }
+
+// Debugf logs per-node decisions (shown with -vv).
+func (l *logger) Debugf(format string, args ...any) { l.logf(logDebug, format, args...) }
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -11,67 +14,266 @@ import (
 )
 
 func main() {
-	parser := sitter.NewParser()
-	defer parser.Close()
-
-	// Wrap the unsafe.Pointer from parserlang.Language()
-	rawLang := parserlang.Language()
-	lang := sitter.NewLanguage(rawLang)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
 
-	// Set the language on the parser
-	e := parser.SetLanguage(lang)
-	if e != nil {
-		panic(fmt.Errorf("couldn't configure parser: %w", e))
+// run is the whole compiler driver; it returns the process exit code so
+// it can be exercised from tests without spawning a binary. stdin is only
+// read when the input is "-".
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("compilador", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	verbose := flags.Bool("v", false, "log compiler phases to stderr")
+	veryVerbose := flags.Bool("vv", false, "log compiler phases and per-node decisions to stderr")
+	recoverErrors := flags.Bool("recover", false, "report syntax errors and keep building the valid parts")
+	noSint := flags.Bool("no-sint", false, "don't write the .sint syntax tree file")
+	showMetrics := flags.Bool("metrics", false, "print node counts and block nesting depth")
+	summaryFormat := flags.String("summary", "", "print a compile summary after the run (json)")
+	emit := flags.String("emit", "", "stop after a stage and output it: cst, ast, sem or json")
+	outputFlag := flags.String("o", "", "write the output file (.sint, or the -emit artifact) to this path")
+	checkOnly := flags.Bool("check", false, "only report diagnostics; print no AST and write no files")
+	warningsAreErrors := flags.Bool("Werror", false, "exit non-zero when there are warnings")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *summaryFormat != "" && *summaryFormat != "json" {
+		fmt.Fprintf(stderr, "error: unknown summary format %q (want json)\n", *summaryFormat)
+		return 2
+	}
+	switch *emit {
+	case "", "cst", "ast", "sem", "json":
+	case "asm", "run":
+		fmt.Fprintf(stderr, "error: -emit=%s is not available: there is no code generator or interpreter yet\n", *emit)
+		return 2
+	default:
+		fmt.Fprintf(stderr, "error: unknown emit stage %q (want cst, ast, sem or json)\n", *emit)
+		return 2
 	}
 
-	if len(os.Args) < 2 {
-		fmt.Fprintln(os.Stderr, "usage: compilador <input.ctds>")
-		os.Exit(1)
+	level := logQuiet
+	if *verbose {
+		level = logInfo
 	}
+	if *veryVerbose {
+		level = logDebug
+	}
+	log := newLogger(stderr, level)
 
-	inputArg := os.Args[1]
+	parser, e := newParser()
+	if e != nil {
+		panic(e)
+	}
+	defer parser.Close()
 
-	if filepath.Ext(inputArg) != ".ctds" {
-		fmt.Fprintln(os.Stderr, "error: input file must have .ctds extension")
-		os.Exit(1)
+	if flags.NArg() < 1 {
+		fmt.Fprintln(stderr, "usage: compilador [-v|-vv] [-recover] [-no-sint] [-metrics] [-summary=json] [-emit=cst|ast|sem|json] [-o path] [-check] [-Werror] <input.ctds | ->")
+		return 1
 	}
 
+	inputArg := flags.Arg(0)
+
 	var code []byte
 	var err error
-	code, err = os.ReadFile(inputArg)
+	if inputArg == "-" {
+		// Reading from a pipe: diagnostics and output files are named
+		// after "stdin".
+		inputArg = "stdin"
+		code, err = io.ReadAll(stdin)
+	} else {
+		if filepath.Ext(inputArg) != ".ctds" {
+			fmt.Fprintln(stderr, "error: input file must have .ctds extension")
+			return 1
+		}
+		code, err = os.ReadFile(inputArg)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error reading input: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "error reading input: %v\n", err)
+		return 1
 	}
+	log.Infof("read %s (%d bytes)", inputArg, len(code))
 
 	// Parse the code
+	log.Infof("parsing")
 	tree := parser.Parse(code, nil)
 	defer tree.Close()
 
 	// Get the root node
 	root := tree.RootNode()
 
+	var ast *Program
+	errCount, warnCount := 0, 0
+	exitCode := func() int {
+		if errCount > 0 || (*warningsAreErrors && warnCount > 0) {
+			return 1
+		}
+		return 0
+	}
+	if *summaryFormat != "" {
+		defer func() {
+			writeSummaryJSON(stdout, summarize(inputArg, ast, errCount, warnCount, exitCode() == 0))
+		}()
+	}
+	// outputPath is where an artifact with the given extension goes: the
+	// -o path, or the input's name with its extension replaced.
+	outputPath := func(ext string) string {
+		if *outputFlag != "" {
+			return *outputFlag
+		}
+		return inputArg[:len(inputArg)-len(filepath.Ext(inputArg))] + ext
+	}
+
+	if *emit == "cst" {
+		for _, err := range syntaxErrors(root, code) {
+			errCount++
+			fmt.Fprintf(stderr, "%s: %v\n", inputArg, err)
+		}
+		if !*checkOnly {
+			if err := writeOutput(log, stdout, outputPath(".sint"), []byte(root.ToSexp())); err != nil {
+				fmt.Fprintf(stderr, "error writing output: %v\n", err)
+				return 1
+			}
+		}
+		return exitCode()
+	}
+
 	if root.HasError() {
-		fmt.Fprintf(os.Stderr, "could not parse file %s: syntax error\n", inputArg)
+		syntaxErrs := syntaxErrors(root, code)
+		errCount = len(syntaxErrs)
+		if !*recoverErrors {
+			fmt.Fprintf(stderr, "could not parse file %s: syntax error\n", inputArg)
 
-		os.Exit(1)
+			return 1
+		}
+		for _, err := range syntaxErrs {
+			fmt.Fprintf(stderr, "%s: %v\n", inputArg, err)
+		}
+
+		log.Infof("building AST for the error-free regions")
+		var errs []error
+		ast, errs = buildAST(root, code, true, log)
+		errCount += len(errs)
+		for _, err := range errs {
+			fmt.Fprintf(stderr, "%s: %v\n", inputArg, err)
+		}
+	} else {
+		log.Infof("building AST")
+		var errs []error
+		ast, errs = buildAST(root, code, false, log)
+		if len(errs) > 0 {
+			errCount++
+			fmt.Fprintf(stderr, "%s: %v\n", inputArg, errs[0])
+		}
+	}
+	if ast != nil {
+		log.Infof("AST has %d globals and %d methods", len(ast.Declarations), len(ast.Methods))
+	}
+
+	// With -emit=sem the diagnostics are the output, printed or written to
+	// the -o file.
+	var diagOut io.Writer = stderr
+	var semOut bytes.Buffer
+	if *emit == "sem" {
+		diagOut = stdout
+		if *outputFlag != "" && !*checkOnly {
+			diagOut = &semOut
+		}
+	}
+	if ast != nil {
+		log.Infof("analyzing")
+		diags := analyze(ast, log)
+		errCount += len(diags.Errors)
+		warnCount += len(diags.Warnings)
+		for _, err := range diags.Errors {
+			fmt.Fprintf(diagOut, "%s: %v\n", inputArg, err)
+		}
+		for _, w := range diags.Warnings {
+			fmt.Fprintf(diagOut, "%s: warning: %v\n", inputArg, w)
+		}
+	}
+	if *emit == "sem" && *outputFlag != "" && !*checkOnly {
+		if err := writeOutput(log, stdout, *outputFlag, semOut.Bytes()); err != nil {
+			fmt.Fprintf(stderr, "error writing output: %v\n", err)
+			return 1
+		}
+	}
+	if *checkOnly || *emit == "sem" {
+		return exitCode()
 	}
 
-	ast, err := BuildAST(root, code)
+	// The optimizations run once the program is known to be valid, so
+	// every output below shows the program a back end would get.
+	if ast != nil && errCount == 0 {
+		log.Infof("optimizing")
+		FoldConstants(ast)
+		EliminateDeadBranches(ast)
+	}
 
-	if err != nil {
-		fmt.Printf("Coudldn't buil AST: %s", err.Error())
+	switch {
+	case *emit == "ast":
+		if ast == nil {
+			return exitCode()
+		}
+		if *outputFlag == "" {
+			printAST(stdout, ast)
+			return exitCode()
+		}
+		var out bytes.Buffer
+		printAST(&out, ast)
+		if err := writeOutput(log, stdout, *outputFlag, out.Bytes()); err != nil {
+			fmt.Fprintf(stderr, "error writing output: %v\n", err)
+			return 1
+		}
+		return exitCode()
+	case *emit == "json":
+		if ast != nil {
+			out, err := ASTToJSON(ast)
+			if err == nil {
+				err = writeOutput(log, stdout, outputPath(".ast.json"), append(out, '\n'))
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "error writing output: %v\n", err)
+				return 1
+			}
+		}
+		return exitCode()
+	}
+
+	fmt.Fprintln(stdout, ast)
+	if *showMetrics && ast != nil {
+		writeMetrics(stdout, computeMetrics(ast))
 	}
-	fmt.Println(ast)
 
 	// Pretty-print the syntax tree and write to .sint file
-	output := []byte(root.ToSexp())
-	base := inputArg[:len(inputArg)-len(filepath.Ext(inputArg))]
-	outputPath := base + ".sint"
-	if err := os.WriteFile(outputPath, output, 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "error writing output: %v\n", err)
-		os.Exit(1)
+	if !*noSint {
+		if err := writeOutput(log, stdout, outputPath(".sint"), []byte(root.ToSexp())); err != nil {
+			fmt.Fprintf(stderr, "error writing output: %v\n", err)
+			return 1
+		}
 	}
+	return exitCode()
+}
+
+// writeOutput writes one of the compiler's artifacts and tells the user
+// where it went.
+func writeOutput(log *logger, stdout io.Writer, path string, data []byte) error {
+	log.Infof("writing %s", path)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "Output written to:", path)
+	return nil
+}
 
-	fmt.Println("Output written to:", outputPath)
+// newParser returns a tree-sitter parser configured for the CTDS grammar.
+func newParser() (*sitter.Parser, error) {
+	parser := sitter.NewParser()
+
+	// Wrap the unsafe.Pointer from parserlang.Language()
+	lang := sitter.NewLanguage(parserlang.Language())
+
+	if err := parser.SetLanguage(lang); err != nil {
+		parser.Close()
+		return nil, fmt.Errorf("couldn't configure parser: %w", err)
+	}
+	return parser, nil
 }
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleProgram = `program {
+	integer x = 1;
+
+	integer inc(integer y) {
+		return y + 1;
+	}
+
+	void main() {
+		x = inc(x);
+	}
+}
+`
+
+// writeSource drops src into a fresh temp dir and returns the file path.
+func writeSource(t *testing.T, name, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+	return path
+}
+
+func TestRunVeryVerboseLogs(t *testing.T) {
+	path := writeSource(t, "prog.ctds", sampleProgram)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-vv", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d, stderr:\n%s", code, stderr.String())
+	}
+	for _, want := range []string{
+		"[debug] method integer inc with 1 params",      // builder
+		"[debug] scope 1: integer y declared at line 4", // analyzer
+	} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("expected log line %q, got:\n%s", want, stderr.String())
+		}
+	}
+}
+
+func TestRunQuietByDefault(t *testing.T) {
+	path := writeSource(t, "prog.ctds", sampleProgram)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d, stderr:\n%s", code, stderr.String())
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("expected no logs without -v, got:\n%s", stderr.String())
+	}
+}
+
+func TestRunSummaryJSON(t *testing.T) {
+	path := writeSource(t, "prog.ctds", sampleProgram)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-summary=json", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d, stderr:\n%s", code, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	var got compileSummary
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &got); err != nil {
+		t.Fatalf("last stdout line is not a JSON summary: %v\n%s", err, stdout.String())
+	}
+	want := compileSummary{
+		File:       path,
+		Methods:    2,
+		Globals:    1,
+		Statements: 2,
+		Succeeded:  true,
+	}
+	if got != want {
+		t.Errorf("summary mismatch:\n got %+v\nwant %+v", got, want)
+	}
+
+	// With -Werror a warning fails the compilation, and the summary says so.
+	path = writeSource(t, "prog.ctds", `program {
+	void main() {
+		integer unused = 0;
+	}
+}
+`)
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"-summary=json", "-Werror", path}, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("run exited %d, want 1, stderr:\n%s", code, stderr.String())
+	}
+	lines = strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	got = compileSummary{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &got); err != nil {
+		t.Fatalf("last stdout line is not a JSON summary: %v\n%s", err, stdout.String())
+	}
+	if got.Warnings != 1 || got.Succeeded {
+		t.Errorf("expected one warning and a failed compilation, got %+v", got)
+	}
+}
+
+func TestRunNoSint(t *testing.T) {
+	path := writeSource(t, "prog.ctds", sampleProgram)
+	sint := strings.TrimSuffix(path, ".ctds") + ".sint"
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-no-sint", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d, stderr:\n%s", code, stderr.String())
+	}
+	if _, err := os.Stat(sint); !os.IsNotExist(err) {
+		t.Errorf("expected %s not to be written, stat err = %v", sint, err)
+	}
+
+	if code := run([]string{path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d, stderr:\n%s", code, stderr.String())
+	}
+	if _, err := os.Stat(sint); err != nil {
+		t.Errorf("expected %s to be written without -no-sint: %v", sint, err)
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		src  string
+		code int
+	}{
+		{"clean", nil, sampleProgram, 0},
+		{"missing return", nil, `program {
+	integer sign(integer y) {
+		if (y > 0) then {
+			return 1;
+		}
+	}
+}
+`, 1},
+		{"warning", nil, `program {
+	void main() {
+		integer i = 10;
+		while (i < 5) {
+			i = i + 1;
+		}
+	}
+}
+`, 0},
+		{"warning with -Werror", []string{"-Werror"}, `program {
+	void main() {
+		integer i = 10;
+		while (i < 5) {
+			i = i + 1;
+		}
+	}
+}
+`, 1},
+		{"unused variable with -Werror", []string{"-Werror"}, `program {
+	void main() {
+		integer unused = 0;
+	}
+}
+`, 1},
+	}
+	for _, tt := range tests {
+		path := writeSource(t, "prog.ctds", tt.src)
+		args := append(append([]string{"-check"}, tt.args...), path)
+
+		var stdout, stderr bytes.Buffer
+		if code := run(args, nil, &stdout, &stderr); code != tt.code {
+			t.Errorf("%s: run exited %d, want %d, stderr:\n%s", tt.name, code, tt.code, stderr.String())
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("%s: expected no output with -check, got:\n%s", tt.name, stdout.String())
+		}
+		entries, err := os.ReadDir(filepath.Dir(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("%s: expected -check to write no files, found %d entries", tt.name, len(entries))
+		}
+	}
+}
+
+func TestRunEmit(t *testing.T) {
+	const unreachable = `program {
+	integer f() {
+		return 1;
+		f();
+	}
+}
+`
+	tests := []struct {
+		stage  string
+		src    string
+		code   int
+		stdout string // expected in stdout
+		file   string // suffix of the only file written next to the source, if any
+	}{
+		{"cst", sampleProgram, 0, "Output written to:", ".sint"},
+		{"ast", sampleProgram, 0, "Method(return=integer, name=inc)", ""},
+		{"sem", unreachable, 1, "line 4: unreachable code after return", ""},
+		{"json", sampleProgram, 0, "Output written to:", ".ast.json"},
+	}
+	for _, tt := range tests {
+		path := writeSource(t, "prog.ctds", tt.src)
+
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"-emit=" + tt.stage, path}, nil, &stdout, &stderr); code != tt.code {
+			t.Errorf("%s: run exited %d, want %d, stderr:\n%s", tt.stage, code, tt.code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), tt.stdout) {
+			t.Errorf("%s: expected %q in output:\n%s", tt.stage, tt.stdout, stdout.String())
+		}
+
+		entries, err := os.ReadDir(filepath.Dir(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var written []string
+		for _, e := range entries {
+			if e.Name() != "prog.ctds" {
+				written = append(written, e.Name())
+			}
+		}
+		want := []string{}
+		if tt.file != "" {
+			want = []string{"prog" + tt.file}
+		}
+		if strings.Join(written, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: expected files %v to be written, got %v", tt.stage, want, written)
+		}
+	}
+}
+
+func TestRunEmitUnavailableStage(t *testing.T) {
+	for _, stage := range []string{"asm", "run", "bogus"} {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"-emit=" + stage, "prog.ctds"}, nil, &stdout, &stderr); code != 2 {
+			t.Errorf("-emit=%s: run exited %d, want 2", stage, code)
+		}
+		if !strings.Contains(stderr.String(), stage) {
+			t.Errorf("-emit=%s: expected the stage in the error, got:\n%s", stage, stderr.String())
+		}
+	}
+}
+
+func TestRunReadsStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-emit=sem", "-"}, strings.NewReader(`program {
+	integer f() {
+		return 1;
+		f();
+	}
+}
+`), &stdout, &stderr)
+	if code != 1 {
+		t.Errorf("run exited %d, want 1, stderr:\n%s", code, stderr.String())
+	}
+	if want := "stdin: line 4: unreachable code after return"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("expected %q in output:\n%s", want, stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"-emit=ast", "-"}, strings.NewReader(sampleProgram), &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d, stderr:\n%s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Method(return=integer, name=inc)") {
+		t.Errorf("expected the AST of the piped program, got:\n%s", stdout.String())
+	}
+}
+
+func TestRunOutputPath(t *testing.T) {
+	path := writeSource(t, "prog.ctds", sampleProgram)
+	dir := filepath.Dir(path)
+
+	tests := []struct {
+		args []string
+		want string // file expected in dir
+	}{
+		{[]string{"-o", filepath.Join(dir, "out.txt")}, "out.txt"},
+		{nil, "prog.sint"},
+		{[]string{"-emit=json", "-o", filepath.Join(dir, "tree.json")}, "tree.json"},
+		{[]string{"-emit=ast", "-o", filepath.Join(dir, "ast.txt")}, "ast.txt"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := run(append(tt.args, path), nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("%v: run exited %d, stderr:\n%s", tt.args, code, stderr.String())
+		}
+		want := filepath.Join(dir, tt.want)
+		if !strings.Contains(stdout.String(), "Output written to: "+want) {
+			t.Errorf("%v: expected the output path in:\n%s", tt.args, stdout.String())
+		}
+		if _, err := os.Stat(want); err != nil {
+			t.Errorf("%v: expected %s to be written: %v", tt.args, want, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "prog.ast.json")); !os.IsNotExist(err) {
+		t.Errorf("expected -o to replace the default .ast.json name, stat err = %v", err)
+	}
+}
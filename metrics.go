@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// astMetrics is the -metrics report: how many nodes of each kind a program
+// has and how deeply its blocks nest.
+type astMetrics struct {
+	Methods      int
+	Declarations int // globals and locals
+	Assignments  int
+	Ifs          int
+	Whiles       int
+	Fors         int
+	Calls        int
+	Expressions  int // every expression node, calls included
+	MaxDepth     int // a method body is depth 1
+}
+
+func computeMetrics(p *Program) astMetrics {
+	var m astMetrics
+	Walk(p, VisitorFunc(func(n Node) bool {
+		switch n.(type) {
+		case *MethodDecl:
+			m.Methods++
+		case *VarDecl:
+			m.Declarations++
+		case *Assignment:
+			m.Assignments++
+		case *IfStmt:
+			m.Ifs++
+		case *WhileStmt:
+			m.Whiles++
+		case *ForStmt:
+			m.Fors++
+		case *CallExpr:
+			m.Calls++
+		}
+		if _, ok := n.(Expr); ok {
+			m.Expressions++
+		}
+		return true
+	}))
+	Walk(p, nestingVisitor{max: &m.MaxDepth})
+	return m
+}
+
+// nestingVisitor tracks block depth: on each Block it walks the block's
+// contents one level deeper and prunes the outer traversal.
+type nestingVisitor struct {
+	depth int
+	max   *int
+}
+
+func (v nestingVisitor) Visit(n Node) bool {
+	b, ok := n.(*Block)
+	if !ok {
+		return true
+	}
+	inner := nestingVisitor{depth: v.depth + 1, max: v.max}
+	if inner.depth > *v.max {
+		*v.max = inner.depth
+	}
+	for _, d := range b.Declarations {
+		Walk(d, inner)
+	}
+	for _, s := range b.Stmts {
+		Walk(s, inner)
+	}
+	return false
+}
+
+func writeMetrics(w io.Writer, m astMetrics) {
+	fmt.Fprintln(w, "metrics:")
+	fmt.Fprintf(w, "  methods: %d\n", m.Methods)
+	fmt.Fprintf(w, "  declarations: %d\n", m.Declarations)
+	fmt.Fprintf(w, "  assignments: %d\n", m.Assignments)
+	fmt.Fprintf(w, "  ifs: %d\n", m.Ifs)
+	fmt.Fprintf(w, "  whiles: %d\n", m.Whiles)
+	fmt.Fprintf(w, "  fors: %d\n", m.Fors)
+	fmt.Fprintf(w, "  calls: %d\n", m.Calls)
+	fmt.Fprintf(w, "  expressions: %d\n", m.Expressions)
+	fmt.Fprintf(w, "  max block depth: %d\n", m.MaxDepth)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestComputeMetrics(t *testing.T) {
+	got := computeMetrics(everyKindProgram())
+	want := astMetrics{
+		Methods:      2,
+		Declarations: 5,
+		Assignments:  1,
+		Ifs:          1,
+		Whiles:       1,
+		Fors:         1,
+		Calls:        1,
+		Expressions:  15,
+		MaxDepth:     2,
+	}
+	if got != want {
+		t.Errorf("metrics mismatch:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestComputeMetricsDeepNesting(t *testing.T) {
+	// while { if { if { } } } inside main: four blocks deep.
+	inner := &IfStmt{Cond: NewBoolLit(true), Then: &Block{}}
+	outer := &IfStmt{Cond: NewBoolLit(true), Then: &Block{Stmts: []Stmt{inner}}}
+	loop := &WhileStmt{Cond: NewBoolLit(true), Body: &Block{Stmts: []Stmt{outer}}}
+	p := &Program{Methods: []*MethodDecl{{
+		Return: &TypeNode{Kind: TypeVoid},
+		Name:   "main",
+		Body:   &Block{Stmts: []Stmt{loop}},
+	}}}
+
+	if got := computeMetrics(p).MaxDepth; got != 4 {
+		t.Errorf("expected max depth 4, got %d", got)
+	}
+}
+
+func TestRunMetrics(t *testing.T) {
+	path := writeSource(t, "prog.ctds", sampleProgram)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-metrics", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d, stderr:\n%s", code, stderr.String())
+	}
+	for _, want := range []string{"methods: 2", "declarations: 1", "calls: 1", "max block depth: 1"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("expected %q in output:\n%s", want, stdout.String())
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+// FoldConstants replaces, in place, every expression of p that only
+// involves literals with the literal it evaluates to, so 2 * (3 + 4)
+// becomes 14. The new literal keeps the line of the expression it
+// replaces. Division and modulo by zero are left alone for the program to
+// fail on at run time.
+func FoldConstants(p *Program) {
+	for _, d := range p.Declarations {
+		d.Value = foldExpr(d.Value)
+	}
+	for _, m := range p.Methods {
+		foldBlock(m.Body)
+	}
+}
+
+func foldBlock(b *Block) {
+	if b == nil {
+		return
+	}
+	for _, d := range b.Declarations {
+		d.Value = foldExpr(d.Value)
+	}
+	for _, st := range b.Stmts {
+		foldStmt(st)
+	}
+}
+
+func foldStmt(st Stmt) {
+	switch st := st.(type) {
+	case *Block:
+		foldBlock(st)
+	case *Assignment:
+		st.Value = foldExpr(st.Value)
+	case *ExprStmt:
+		st.Expr = foldExpr(st.Expr)
+	case *ReturnStmt:
+		st.Value = foldExpr(st.Value)
+	case *IfStmt:
+		st.Cond = foldExpr(st.Cond)
+		foldBlock(st.Then)
+		foldBlock(st.Else)
+	case *WhileStmt:
+		st.Cond = foldExpr(st.Cond)
+		foldBlock(st.Body)
+	case *ForStmt:
+		if st.Var != nil {
+			st.Var.Value = foldExpr(st.Var.Value)
+		}
+		st.Bound = foldExpr(st.Bound)
+		foldBlock(st.Body)
+	}
+}
+
+// foldExpr folds the operands of e first, then e itself when they all
+// turned into literals.
+func foldExpr(e Expr) Expr {
+	switch n := e.(type) {
+	case *UnaryExpr:
+		n.Expr = foldExpr(n.Expr)
+		if !isLiteral(n.Expr) {
+			return n
+		}
+	case *BinaryExpr:
+		n.Left = foldExpr(n.Left)
+		n.Right = foldExpr(n.Right)
+		if !isLiteral(n.Left) || !isLiteral(n.Right) {
+			return n
+		}
+	case *ParenExpr:
+		n.Inner = foldExpr(n.Inner)
+		if !isLiteral(n.Inner) {
+			return n
+		}
+	case *CallExpr:
+		for i, a := range n.Args {
+			n.Args[i] = foldExpr(a)
+		}
+		return n
+	default:
+		return e
+	}
+
+	v, ok := evalConst(e, nil)
+	if !ok {
+		return e
+	}
+	switch v := v.(type) {
+	case int:
+		return &IntLiteral{NodeBase: NodeBase{Line: lineOf(e)}, Value: v, Type: TypeInteger}
+	case bool:
+		return &BoolLiteral{NodeBase: NodeBase{Line: lineOf(e)}, Value: v, Type: TypeBool}
+	}
+	return e
+}
+
+func isLiteral(e Expr) bool {
+	switch e.(type) {
+	case *IntLiteral, *BoolLiteral:
+		return true
+	}
+	return false
+}
+
+// EliminateDeadBranches rewrites, in place, every if whose condition is a
+// boolean literal into the statements of the branch that runs, and drops
+// while (false) loops. It expects FoldConstants to have run first so that
+// conditions like 1 > 2 are already literals.
+func EliminateDeadBranches(p *Program) {
+	for _, m := range p.Methods {
+		pruneBlock(m.Body)
+	}
+}
+
+func pruneBlock(b *Block) {
+	if b == nil {
+		return
+	}
+	stmts := make([]Stmt, 0, len(b.Stmts))
+	for _, st := range b.Stmts {
+		stmts = append(stmts, pruneStmt(st)...)
+	}
+	b.Stmts = stmts
+}
+
+// pruneStmt returns what st becomes: itself, the statements of the branch
+// that always runs, or nothing.
+func pruneStmt(st Stmt) []Stmt {
+	switch st := st.(type) {
+	case *Block:
+		pruneBlock(st)
+	case *IfStmt:
+		pruneBlock(st.Then)
+		pruneBlock(st.Else)
+		if cond, ok := st.Cond.(*BoolLiteral); ok {
+			if cond.Value {
+				return inline(st.Then)
+			}
+			return inline(st.Else)
+		}
+	case *WhileStmt:
+		if cond, ok := st.Cond.(*BoolLiteral); ok && !cond.Value {
+			return nil
+		}
+		pruneBlock(st.Body)
+	case *ForStmt:
+		pruneBlock(st.Body)
+	}
+	return []Stmt{st}
+}
+
+// inline returns the statements of b to splice into the enclosing block.
+// A block with its own declarations is kept whole so they stay scoped.
+func inline(b *Block) []Stmt {
+	if b == nil {
+		return nil
+	}
+	if len(b.Declarations) > 0 {
+		return []Stmt{b}
+	}
+	return b.Stmts
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// foldReturn folds `return <e>;` inside a method and gives back the result.
+func foldReturn(e Expr) Expr {
+	ret := &ReturnStmt{Value: e}
+	FoldConstants(&Program{Methods: []*MethodDecl{method(TypeInteger, "f", ret)}})
+	return ret.Value
+}
+
+func TestFoldConstantsArithmetic(t *testing.T) {
+	// 2 * (3 + 4)
+	e := &BinaryExpr{
+		NodeBase: NodeBase{Line: 7},
+		Left:     NewIntLit(2),
+		Op:       BinMul,
+		Right:    &ParenExpr{Inner: &BinaryExpr{Left: NewIntLit(3), Op: BinAdd, Right: NewIntLit(4)}},
+	}
+	got := foldReturn(e)
+	lit, ok := got.(*IntLiteral)
+	if !ok {
+		t.Fatalf("expected an IntLiteral, got %#v", got)
+	}
+	if lit.Value != 14 || lit.Line != 7 {
+		t.Errorf("expected 14 at line 7, got %d at line %d", lit.Value, lit.Line)
+	}
+}
+
+func TestFoldConstantsBoolAndUnary(t *testing.T) {
+	// !(1 > 2) && true
+	e := &BinaryExpr{
+		Left:  &UnaryExpr{Op: UnaryNot, Expr: &ParenExpr{Inner: &BinaryExpr{Left: NewIntLit(1), Op: BinGT, Right: NewIntLit(2)}}},
+		Op:    BinAnd,
+		Right: NewBoolLit(true),
+	}
+	got := foldReturn(e)
+	if lit, ok := got.(*BoolLiteral); !ok || !lit.Value {
+		t.Errorf("expected true, got %#v", got)
+	}
+	got = foldReturn(&UnaryExpr{Op: UnaryNeg, Expr: NewIntLit(3)})
+	if lit, ok := got.(*IntLiteral); !ok || lit.Value != -3 {
+		t.Errorf("expected -3, got %#v", got)
+	}
+}
+
+func TestFoldConstantsKeepsDivisionByZero(t *testing.T) {
+	// (1 + 1) / 0 keeps the division but folds its left operand.
+	e := &BinaryExpr{
+		Left:  &BinaryExpr{Left: NewIntLit(1), Op: BinAdd, Right: NewIntLit(1)},
+		Op:    BinDiv,
+		Right: NewIntLit(0),
+	}
+	folded := foldReturn(e)
+	got, ok := folded.(*BinaryExpr)
+	if !ok {
+		t.Fatalf("division by zero was folded into %#v", folded)
+	}
+	if lit, ok := got.Left.(*IntLiteral); !ok || lit.Value != 2 {
+		t.Errorf("expected the left operand to fold to 2, got %#v", got.Left)
+	}
+}
+
+func TestFoldConstantsLeavesVariables(t *testing.T) {
+	// y + (2 * 3) becomes y + 6.
+	e := &BinaryExpr{Left: NewIdent("y"), Op: BinAdd, Right: &ParenExpr{Inner: &BinaryExpr{Left: NewIntLit(2), Op: BinMul, Right: NewIntLit(3)}}}
+	folded := foldReturn(e)
+	got, ok := folded.(*BinaryExpr)
+	if !ok {
+		t.Fatalf("expected a BinaryExpr, got %#v", folded)
+	}
+	if lit, ok := got.Right.(*IntLiteral); !ok || lit.Value != 6 {
+		t.Errorf("expected the right operand to fold to 6, got %#v", got.Right)
+	}
+}
+
+// stmtLines returns the line of each statement in b, to check what
+// survived and in which order.
+func stmtLines(b *Block) []int {
+	var lines []int
+	for _, st := range b.Stmts {
+		lines = append(lines, lineOf(st))
+	}
+	return lines
+}
+
+func TestEliminateDeadBranches(t *testing.T) {
+	tests := []struct {
+		name string
+		cond bool
+		want []int
+	}{
+		{"true keeps then", true, []int{1, 3, 4, 7}},
+		{"false keeps else", false, []int{1, 5, 7}},
+	}
+	for _, tt := range tests {
+		ifStmt := &IfStmt{
+			NodeBase: NodeBase{Line: 2},
+			Cond:     NewBoolLit(tt.cond),
+			Then:     &Block{Stmts: []Stmt{callAt(3), callAt(4)}},
+			Else:     &Block{Stmts: []Stmt{callAt(5)}},
+		}
+		m := method(TypeVoid, "f", callAt(1), ifStmt, callAt(7))
+		EliminateDeadBranches(&Program{Methods: []*MethodDecl{m}})
+
+		if got := stmtLines(m.Body); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: expected statements at lines %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestEliminateDeadBranchesKeepsScopedBlock(t *testing.T) {
+	then := &Block{
+		NodeBase:     NodeBase{Line: 2},
+		Declarations: []*VarDecl{{Type: &TypeNode{Kind: TypeInteger}, Name: "x", Value: NewIntLit(1)}},
+		Stmts:        []Stmt{callAt(3)},
+	}
+	m := method(TypeVoid, "f", &IfStmt{Cond: NewBoolLit(true), Then: then})
+	EliminateDeadBranches(&Program{Methods: []*MethodDecl{m}})
+
+	if len(m.Body.Stmts) != 1 || m.Body.Stmts[0] != then {
+		t.Errorf("expected the then block to replace the if, got %#v", m.Body.Stmts)
+	}
+}
+
+func TestEliminateWhileFalse(t *testing.T) {
+	// while (1 > 2): only a literal once folded.
+	never := &WhileStmt{
+		Cond: &BinaryExpr{Left: NewIntLit(1), Op: BinGT, Right: NewIntLit(2)},
+		Body: &Block{Stmts: []Stmt{callAt(3)}},
+	}
+	always := &WhileStmt{NodeBase: NodeBase{Line: 4}, Cond: NewBoolLit(true), Body: &Block{
+		Stmts: []Stmt{&IfStmt{Cond: NewBoolLit(false), Then: &Block{Stmts: []Stmt{callAt(5)}}}},
+	}}
+	m := method(TypeVoid, "f", callAt(1), never, always)
+	p := &Program{Methods: []*MethodDecl{m}}
+	FoldConstants(p)
+	EliminateDeadBranches(p)
+
+	if got := stmtLines(m.Body); fmt.Sprint(got) != "[1 4]" {
+		t.Errorf("expected statements at lines [1 4], got %v", got)
+	}
+	if len(always.Body.Stmts) != 0 {
+		t.Errorf("expected the if (false) inside the loop to be removed, got %#v", always.Body.Stmts)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printAST writes p as an indented tree, one node per line, for debugging.
+// Children go two spaces deeper than their parent; the branches of an if
+// and the bodies of loops and methods are introduced by a label line
+// (Then, Else, Body). Each node ends with @L<line>, the source line it
+// starts on, unless the line is unknown (nodes not built from source).
+func printAST(w io.Writer, p *Program) {
+	pr := &printer{w: w}
+	pr.line(0, nil, "Program")
+	for _, d := range p.Declarations {
+		pr.decl(1, d)
+	}
+	for _, m := range p.Methods {
+		pr.method(1, m)
+	}
+}
+
+type printer struct {
+	w io.Writer
+}
+
+// line prints one node of the tree; n gives the line annotation and may be
+// nil for the program.
+func (pr *printer) line(depth int, n Node, format string, args ...any) {
+	text := strings.Repeat("  ", depth) + fmt.Sprintf(format, args...)
+	if l := lineOf(n); l > 0 {
+		text += fmt.Sprintf(" @L%d", l)
+	}
+	fmt.Fprintln(pr.w, text)
+}
+
+func (pr *printer) decl(depth int, d *VarDecl) {
+	pr.line(depth, d, "Decl(type=%s, name=%s)", typeName(d.Type), d.Name)
+	pr.expr(depth+1, d.Value)
+}
+
+func (pr *printer) method(depth int, m *MethodDecl) {
+	if m.Extern {
+		pr.line(depth, m, "Method(return=%s, name=%s, extern)", typeName(m.Return), m.Name)
+	} else {
+		pr.line(depth, m, "Method(return=%s, name=%s)", typeName(m.Return), m.Name)
+	}
+	for _, p := range m.Params {
+		pr.line(depth+1, p, "Param(type=%s, name=%s)", typeName(p.Type), p.Name)
+	}
+	pr.block(depth+1, "Body", m.Body)
+}
+
+// block prints b's declarations and statements under a label line; a nil
+// block prints nothing.
+func (pr *printer) block(depth int, label string, b *Block) {
+	if b == nil {
+		return
+	}
+	pr.line(depth, b, "%s", label)
+	for _, d := range b.Declarations {
+		pr.decl(depth+1, d)
+	}
+	for _, st := range b.Stmts {
+		pr.stmt(depth+1, st)
+	}
+}
+
+func (pr *printer) stmt(depth int, st Stmt) {
+	switch st := st.(type) {
+	case *Block:
+		pr.block(depth, "Block", st)
+	case *Assignment:
+		pr.line(depth, st, "Assign(name=%s)", st.Target)
+		pr.expr(depth+1, st.Value)
+	case *ExprStmt:
+		pr.line(depth, st, "ExprStmt")
+		pr.expr(depth+1, st.Expr)
+	case *ReturnStmt:
+		pr.line(depth, st, "Return")
+		pr.expr(depth+1, st.Value)
+	case *IfStmt:
+		pr.line(depth, st, "If")
+		pr.expr(depth+1, st.Cond)
+		pr.block(depth+1, "Then", st.Then)
+		pr.block(depth+1, "Else", st.Else)
+	case *WhileStmt:
+		pr.line(depth, st, "While")
+		pr.expr(depth+1, st.Cond)
+		pr.block(depth+1, "Body", st.Body)
+	case *ForStmt:
+		pr.line(depth, st, "For")
+		if st.Var != nil {
+			pr.decl(depth+1, st.Var)
+		}
+		pr.expr(depth+1, st.Bound)
+		pr.block(depth+1, "Body", st.Body)
+	default:
+		pr.line(depth, st, "<unknown>")
+	}
+}
+
+// expr prints e and its operands; a nil e (a bare return) prints nothing.
+func (pr *printer) expr(depth int, e Expr) {
+	switch e := e.(type) {
+	case nil:
+	case *IdentExpr:
+		pr.line(depth, e, "Identifier(%s)", e.Name)
+	case *IntLiteral:
+		pr.line(depth, e, "IntLiteral(%d)", e.Value)
+	case *BoolLiteral:
+		pr.line(depth, e, "BoolLiteral(%t)", e.Value)
+	case *StringLiteral:
+		pr.line(depth, e, "StringLiteral(%q)", e.Value)
+	case *CharLiteral:
+		pr.line(depth, e, "CharLiteral(%q)", e.Value)
+	case *UnaryExpr:
+		pr.line(depth, e, "UnaryExpr(%s)", e.Op)
+		pr.expr(depth+1, e.Expr)
+	case *BinaryExpr:
+		pr.line(depth, e, "BinaryExpr(%s)", e.Op)
+		pr.expr(depth+1, e.Left)
+		pr.expr(depth+1, e.Right)
+	case *CallExpr:
+		pr.line(depth, e, "Call(%s)", e.Callee)
+		for _, a := range e.Args {
+			pr.expr(depth+1, a)
+		}
+	case *ParenExpr:
+		pr.line(depth, e, "Paren")
+		pr.expr(depth+1, e.Inner)
+	default:
+		pr.line(depth, e, "<unknown>")
+	}
+}
+
+func typeName(t *TypeNode) string {
+	if t == nil {
+		return TypeVoid.String()
+	}
+	return t.Kind.String()
+}
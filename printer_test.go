@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name, or rewrites the file when
+// the tests run with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n got:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestPrintASTEveryNodeKind(t *testing.T) {
+	p := everyKindProgram()
+	// An else branch and a nested block, which everyKindProgram lacks.
+	ifStmt := p.Methods[1].Body.Stmts[1].(*IfStmt)
+	ifStmt.Else = &Block{Stmts: []Stmt{&Block{Stmts: []Stmt{
+		&Assignment{Target: "b", Value: NewBoolLit(false)},
+	}}}}
+
+	var out bytes.Buffer
+	printAST(&out, p)
+	checkGolden(t, "every_kind.golden", out.Bytes())
+}
+
+func TestPrintASTLineAnnotations(t *testing.T) {
+	src := `program {
+	integer x = 1;
+
+	integer inc(integer y) {
+		if (y > 0) then {
+			return y + 1;
+		}
+		return x;
+	}
+}
+`
+	p, err := BuildAST(parseSource(t, src), []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	printAST(&out, p)
+	want := `Program
+  Decl(type=integer, name=x) @L2
+    IntLiteral(1) @L2
+  Method(return=integer, name=inc) @L4
+    Param(type=integer, name=y) @L4
+    Body @L4
+      If @L5
+        BinaryExpr(>) @L5
+          Identifier(y) @L5
+          IntLiteral(0) @L5
+        Then @L5
+          Return @L6
+            BinaryExpr(+) @L6
+              Identifier(y) @L6
+              IntLiteral(1) @L6
+      Return @L8
+        Identifier(x) @L8
+`
+	if out.String() != want {
+		t.Errorf("printed AST mismatch:\n got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestPrintASTOmitsUnknownLines(t *testing.T) {
+	m := method(TypeVoid, "f", &ExprStmt{NodeBase: NodeBase{Line: 3}, Expr: &CallExpr{Callee: "g"}})
+	m.Line = 2
+
+	var out bytes.Buffer
+	printAST(&out, &Program{Methods: []*MethodDecl{m}})
+	want := `Program
+  Method(return=void, name=f) @L2
+    Body
+      ExprStmt @L3
+        Call(g)
+`
+	if out.String() != want {
+		t.Errorf("printed AST mismatch:\n got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
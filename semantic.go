@@ -0,0 +1,727 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Diagnostics is what one analysis found. Errors make the program invalid;
+// warnings only point at suspicious code.
+type Diagnostics struct {
+	Errors   []error
+	Warnings []error
+}
+
+// AnalyzeWithDiagnostics runs the semantic checks over a built AST and
+// returns every error and warning found, in source order within each method.
+// The warnings include locals and parameters that are never read.
+func AnalyzeWithDiagnostics(p *Program) Diagnostics {
+	return analyze(p, nil)
+}
+
+// analyze is AnalyzeWithDiagnostics, logging the scopes it opens and the
+// names it declares in them to log (which may be nil).
+func analyze(p *Program, log *logger) Diagnostics {
+	// Copied so the scopes pushed during the analysis never touch p.
+	an := &Analyzer{env: append(Env(nil), topLevel(p)...), log: log}
+	for _, d := range p.Declarations {
+		an.checkDecl(d)
+	}
+	for _, m := range p.Methods {
+		an.analyzeMethod(m)
+		an.warns = append(an.warns, unusedVars(m)...)
+	}
+	return Diagnostics{Errors: an.errs, Warnings: an.warns}
+}
+
+// Analyze runs the semantic checks and returns nil for a valid program, or
+// all the errors found joined into one. Warnings are dropped; use
+// AnalyzeWithDiagnostics to get them.
+func Analyze(p *Program) error {
+	return errors.Join(AnalyzeWithDiagnostics(p).Errors...)
+}
+
+// Analyzer holds the state of one Analyze run.
+type Analyzer struct {
+	env    Env         // names visible at the point being analyzed
+	method *MethodDecl // the method being analyzed, what returns are checked against
+	consts constEnv    // locals whose value is known at the statement being analyzed
+	errs   []error
+	warns  []error
+	log    *logger
+}
+
+func (an *Analyzer) errorf(line int, format string, args ...any) {
+	an.errs = append(an.errs, fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...)))
+}
+
+func (an *Analyzer) warnf(line int, format string, args ...any) {
+	an.warns = append(an.warns, fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...)))
+}
+
+// declare inserts a variable into the innermost scope.
+func (an *Analyzer) declare(name Identifier, t *TypeNode, line int) {
+	sym := varSymbol(t, line)
+	an.env.Insert(name, sym)
+	an.log.Debugf("scope %d: %s %s declared at line %d", len(an.env)-1, sym.Type, name, line)
+}
+
+func (an *Analyzer) analyzeMethod(m *MethodDecl) {
+	if m.Body == nil { // extern
+		return
+	}
+	an.log.Debugf("analyzing method %s", m.Name)
+	an.method = m
+	an.env.Push()
+	defer an.env.Pop()
+	for _, p := range m.Params {
+		an.declare(p.Name, p.Type, lineOf(p))
+	}
+	if an.analyzeBlock(m.Body, constEnv{}) == flowNext && m.Return != nil && m.Return.Kind != TypeVoid {
+		an.errorf(lineOf(m), "non-void method '%s' may not return a value", m.Name)
+	}
+}
+
+// flow is how execution leaves a statement or block. The values are
+// ordered: a branch leaves an if the way the lesser of its branches does.
+type flow int
+
+const (
+	flowNext    flow = iota // may continue with the next statement
+	flowForever             // never continues, but not every path returns: a while (true) loop
+	flowReturns             // every path ends in a return
+)
+
+// analyzeBlock checks b and reports how execution leaves it. consts holds
+// the locals whose value is known on entry to b.
+func (an *Analyzer) analyzeBlock(b *Block, consts constEnv) flow {
+	if b == nil {
+		return flowNext
+	}
+	an.env.Push()
+	defer an.env.Pop()
+	consts = consts.clone()
+	an.consts = consts
+	for _, d := range b.Declarations {
+		an.checkDecl(d)
+		an.declare(d.Name, d.Type, lineOf(d))
+		consts.set(d.Name, d.Value)
+	}
+	out := flowNext
+	for _, st := range b.Stmts {
+		if out == flowReturns {
+			// Only the first dead statement is reported; the rest of the
+			// block is dead for the same reason.
+			an.errorf(lineOf(st), "unreachable code after return")
+			break
+		}
+		if f := an.analyzeStmt(st, consts); out == flowNext {
+			out = f
+		}
+		// Only locals are tracked: any call may change a global.
+		if a, ok := st.(*Assignment); ok && an.env.scopeOf(a.Target) > 0 {
+			consts.set(a.Target, a.Value)
+		} else {
+			consts.forgetAssigned(st)
+		}
+	}
+	return out
+}
+
+// analyzeStmt checks st and reports how execution leaves it.
+func (an *Analyzer) analyzeStmt(st Stmt, consts constEnv) flow {
+	an.consts = consts
+	switch st := st.(type) {
+	case *Assignment:
+		an.checkAssignment(st)
+	case *ExprStmt:
+		an.checkExpr(st.Expr, false)
+	case *ReturnStmt:
+		an.checkReturn(st)
+		return flowReturns
+	case *Block:
+		return an.analyzeBlock(st, consts)
+	case *IfStmt:
+		an.checkExpr(st.Cond, false)
+		return min(an.analyzeBlock(st.Then, consts), an.analyzeBlock(st.Else, consts))
+	case *WhileStmt:
+		an.checkExpr(st.Cond, false)
+		if v, _ := evalConst(st.Cond, consts); v == false {
+			an.warnf(lineOf(st), "loop body is never executed")
+		}
+		body := consts.loopEntry(st.Body)
+		an.analyzeBlock(st.Body, body)
+		// There is no break: a loop whose condition stays true only ends
+		// through a return. Any other loop may run zero times.
+		if v, _ := evalConst(st.Cond, body); v == true {
+			return flowForever
+		}
+	case *ForStmt:
+		an.checkExpr(st.Bound, false)
+		// A string bound has already been reported by checkExpr.
+		if t, ok := an.typeOf(st.Bound); ok && t != TypeString && !t.IsInteger() {
+			an.errorf(lineOf(st), "for loop bound must be integer, got %s", t)
+		}
+		body := consts.loopEntry(st.Body)
+		an.env.Push()
+		defer an.env.Pop()
+		if st.Var != nil {
+			if st.Var.Type != nil && !st.Var.Type.Kind.IsInteger() {
+				an.errorf(lineOf(st.Var), "for loop variable '%s' must be integer, got %s", st.Var.Name, st.Var.Type.Kind)
+			}
+			an.checkDecl(st.Var)
+			an.declare(st.Var.Name, st.Var.Type, lineOf(st.Var))
+			delete(body, st.Var.Name)
+		}
+		an.analyzeBlock(st.Body, body)
+	}
+	return flowNext
+}
+
+// checkAssignment reports assigning to a name that is not declared or is
+// not a variable, and assigning a value whose type doesn't match the
+// variable's.
+func (an *Analyzer) checkAssignment(a *Assignment) {
+	sym, ok := an.env.Lookup(a.Target)
+	switch {
+	case !ok:
+		an.errorf(lineOf(a), "undeclared variable '%s'", a.Target)
+	case !sym.isVar:
+		an.errorf(lineOf(a), "cannot assign to method '%s'", a.Target)
+	case sym.Type != TypeVoid:
+		if t, ok := an.storable(a.Value, sym.Type, lineOf(a)); !ok {
+			an.errorf(lineOf(a), "cannot assign a value of type %s to %s variable '%s'", t, sym.Type, a.Target)
+			return
+		}
+	}
+	an.checkExpr(a.Value, true)
+}
+
+// checkReturn reports a return that doesn't match the method's return
+// type: a value in a void method, no value in any other, or a value of the
+// wrong type.
+func (an *Analyzer) checkReturn(r *ReturnStmt) {
+	want := TypeVoid
+	if an.method.Return != nil {
+		want = an.method.Return.Kind
+	}
+	switch {
+	case r.Value == nil:
+		if want != TypeVoid {
+			an.errorf(lineOf(r), "method '%s' must return a value of type %s", an.method.Name, want)
+		}
+		return
+	case want == TypeVoid:
+		an.errorf(lineOf(r), "void method '%s' cannot return a value", an.method.Name)
+		return
+	}
+	an.checkExpr(r.Value, false)
+	// A string has already been reported by checkExpr.
+	if t, ok := an.storable(r.Value, want, lineOf(r)); !ok && t != TypeString {
+		an.errorf(lineOf(r), "cannot return a value of type %s from %s method '%s'", t, want, an.method.Name)
+	}
+}
+
+// checkDecl reports a declaration whose initializer's type doesn't match
+// the variable's.
+func (an *Analyzer) checkDecl(d *VarDecl) {
+	if d.Type == nil {
+		return
+	}
+	if t, ok := an.storable(d.Value, d.Type.Kind, lineOf(d)); !ok {
+		an.errorf(lineOf(d), "cannot initialize %s variable '%s' with a value of type %s", d.Type.Kind, d.Name, t)
+		return
+	}
+	an.checkExpr(d.Value, d.Type.Kind == TypeString)
+}
+
+// compatible reports whether a value of type a can be stored in a b. An
+// integer fits in any integer type at least as wide, so narrowing is
+// rejected; any other type only matches itself.
+func compatible(a, b TypeKind) bool {
+	if a.IsInteger() && b.IsInteger() {
+		return a.Bits() <= b.Bits()
+	}
+	return a == b
+}
+
+// storable reports whether e can be stored in a t, and gives the type of e
+// for the caller to report when it can't. A constant integer is checked
+// against the range of t instead, and reported here at line when it
+// doesn't fit.
+func (an *Analyzer) storable(e Expr, t TypeKind, line int) (TypeKind, bool) {
+	if v, ok := evalConst(e, nil); ok {
+		if n, ok := v.(int); ok && t.IsInteger() {
+			if min, max := t.Range(); n < min || n > max {
+				an.errorf(line, "constant %d overflows %s (%d to %d)", n, t, min, max)
+			}
+			return t, true
+		}
+	}
+	got, ok := an.typeOf(e)
+	return got, !ok || compatible(got, t)
+}
+
+// checkExpr reports names in e that are not declared, and strings
+// (literals, string variables and calls that return a string) anywhere in e
+// except where a string is allowed: as a method call argument, or as the
+// whole value assigned to a variable (stringOK).
+func (an *Analyzer) checkExpr(e Expr, stringOK bool) {
+	switch e := e.(type) {
+	case *StringLiteral:
+		an.checkString(e, stringOK)
+	case *IdentExpr:
+		if _, ok := an.env.Lookup(e.Name); !ok {
+			an.errorf(lineOf(e), "undeclared variable '%s'", e.Name)
+			return
+		}
+		an.checkString(e, stringOK)
+	case *CallExpr:
+		an.checkString(e, stringOK)
+		an.checkCall(e)
+		for _, a := range e.Args {
+			an.checkExpr(a, true)
+		}
+	case *ParenExpr:
+		an.checkExpr(e.Inner, stringOK)
+	case *UnaryExpr:
+		an.checkExpr(e.Expr, false)
+	case *BinaryExpr:
+		an.checkExpr(e.Left, false)
+		an.checkExpr(e.Right, false)
+		an.checkBinary(e)
+	}
+}
+
+// checkString reports e when it is a string and no string is allowed.
+func (an *Analyzer) checkString(e Expr, stringOK bool) {
+	if t, ok := an.typeOf(e); ok && t == TypeString && !stringOK {
+		an.errorf(lineOf(e), "a string can only be passed to a method or assigned to a string variable")
+	}
+}
+
+// checkCall reports calling a name that is not declared or is not a
+// method, and calls whose arguments don't match the method's parameters.
+func (an *Analyzer) checkCall(c *CallExpr) {
+	sym, ok := an.env.Lookup(c.Callee)
+	if !ok {
+		an.errorf(lineOf(c), "undeclared method '%s'", c.Callee)
+		return
+	}
+	if sym.isVar {
+		an.errorf(lineOf(c), "'%s' is not a method", c.Callee)
+		return
+	}
+	if sym.Func == nil {
+		return
+	}
+	if len(c.Args) != sym.Func.Arity {
+		an.errorf(lineOf(c), "method '%s' expects %d arguments, got %d", c.Callee, sym.Func.Arity, len(c.Args))
+		return
+	}
+	for i, a := range c.Args {
+		want := sym.Func.Params[i].Type.Kind
+		// An argument is reported on its own line, which in a call split
+		// over several lines is not the call's.
+		line := lineOf(a)
+		if line == 0 { // an AST put together by hand may leave it out
+			line = lineOf(c)
+		}
+		if got, ok := an.storable(a, want, line); !ok {
+			an.errorf(line, "argument %d of '%s' must be %s, got %s", i+1, c.Callee, want, got)
+		}
+	}
+}
+
+// checkBinary reports operands of the wrong type: arithmetic, < and >
+// take integers, && and || take bools, and == and != need both sides of
+// the same type. Operands whose type is unknown are not checked. It also
+// reports constant arithmetic that overflows, and warns about dividing by
+// a value known to be zero.
+func (an *Analyzer) checkBinary(e *BinaryExpr) {
+	l, lok := an.typeOf(e.Left)
+	r, rok := an.typeOf(e.Right)
+	// A string operand has already been reported by checkExpr.
+	lok = lok && l != TypeString
+	rok = rok && r != TypeString
+
+	switch e.Op {
+	case BinAdd, BinSub, BinMul, BinDiv, BinMod:
+		if lok && !l.IsInteger() {
+			an.errorf(lineOf(e), "operand of '%s' must be integer, got %s", e.Op, l)
+		} else if rok && !r.IsInteger() {
+			an.errorf(lineOf(e), "operand of '%s' must be integer, got %s", e.Op, r)
+		} else if l, r, ok := constOperands(e); ok && overflows(e.Op, l, r) {
+			an.errorf(lineOf(e), "integer overflow in constant expression")
+		}
+		if v, _ := evalConst(e.Right, an.consts); (e.Op == BinDiv || e.Op == BinMod) && v == 0 {
+			an.warnf(lineOf(e), "division by zero")
+		}
+	case BinAnd, BinOr:
+		if lok && l != TypeBool {
+			an.errorf(lineOf(e), "operand of '%s' must be bool, got %s", e.Op, l)
+		} else if rok && r != TypeBool {
+			an.errorf(lineOf(e), "operand of '%s' must be bool, got %s", e.Op, r)
+		}
+	case BinLT, BinGT:
+		if lok && !l.IsInteger() {
+			an.errorf(lineOf(e), "operand of '%s' must be integer, got %s", e.Op, l)
+		} else if rok && !r.IsInteger() {
+			an.errorf(lineOf(e), "operand of '%s' must be integer, got %s", e.Op, r)
+		}
+	case BinEq, BinNeq:
+		if lok && rok && !compatible(l, r) && !compatible(r, l) {
+			an.errorf(lineOf(e), "cannot compare %s with %s", l, r)
+		}
+	}
+}
+
+// typeOf is the type of e, when it is known: variables and calls take the
+// type they were declared with in the current scope, and names that are
+// not declared have no known type.
+func (an *Analyzer) typeOf(e Expr) (TypeKind, bool) {
+	switch e := e.(type) {
+	case *IdentExpr:
+		if sym, ok := an.env.Lookup(e.Name); ok && sym.isVar {
+			return sym.Type, sym.Type != TypeVoid
+		}
+	case *CallExpr:
+		if sym, ok := an.env.Lookup(e.Callee); ok && sym.Func != nil {
+			return sym.Func.Return, true
+		}
+	case *IntLiteral:
+		return TypeInteger, true
+	case *BoolLiteral:
+		return TypeBool, true
+	case *StringLiteral:
+		return TypeString, true
+	case *CharLiteral:
+		return TypeChar, true
+	case *ParenExpr:
+		return an.typeOf(e.Inner)
+	case *UnaryExpr:
+		if t, ok := an.typeOf(e.Expr); ok && e.Op == UnaryNeg && t.IsInteger() {
+			return t, true
+		}
+		return e.Type, e.Type != TypeVoid
+	case *BinaryExpr:
+		switch e.Op {
+		case BinAdd, BinSub, BinMul, BinDiv, BinMod:
+			if t, ok := an.arithType(e); ok {
+				return t, true
+			}
+		}
+		return e.Type, e.Type != TypeVoid
+	}
+	return TypeVoid, false
+}
+
+// arithType is the integer type of arithmetic on two integers: the wider
+// of the two, where a constant operand takes the type of the other one so
+// that b + 1 stays an int8.
+func (an *Analyzer) arithType(e *BinaryExpr) (TypeKind, bool) {
+	l, lok := an.typeOf(e.Left)
+	r, rok := an.typeOf(e.Right)
+	if _, ok := evalConst(e.Left, nil); ok {
+		l, lok = r, rok
+	}
+	if _, ok := evalConst(e.Right, nil); ok {
+		r, rok = l, lok
+	}
+	if !lok || !rok || !l.IsInteger() || !r.IsInteger() {
+		return TypeVoid, false
+	}
+	if l.Bits() >= r.Bits() {
+		return l, true
+	}
+	return r, true
+}
+
+// constEnv maps a local to its value (an int or a bool) at some point of a
+// method, for the locals whose value is known there.
+type constEnv map[Identifier]any
+
+func (env constEnv) clone() constEnv {
+	c := make(constEnv, len(env))
+	for k, v := range env {
+		c[k] = v
+	}
+	return c
+}
+
+// set records that name now holds the value of e, or forgets name when e
+// is not a constant.
+func (env constEnv) set(name Identifier, e Expr) {
+	if v, ok := evalConst(e, env); ok {
+		env[name] = v
+	} else {
+		delete(env, name)
+	}
+}
+
+// forgetAssigned drops every variable assigned anywhere inside n.
+func (env constEnv) forgetAssigned(n Node) {
+	if n == nil {
+		return
+	}
+	Walk(n, VisitorFunc(func(n Node) bool {
+		if a, ok := n.(*Assignment); ok {
+			delete(env, a.Target)
+		}
+		return true
+	}))
+}
+
+// loopEntry returns the values known at the top of every iteration of a
+// loop with the given body: those the body never assigns.
+func (env constEnv) loopEntry(body *Block) constEnv {
+	c := env.clone()
+	if body != nil {
+		c.forgetAssigned(body)
+	}
+	return c
+}
+
+// evalConst computes e when it only depends on literals and on the locals
+// in env. Calls, unknown variables and division by zero make it fail.
+func evalConst(e Expr, env constEnv) (any, bool) {
+	switch e := e.(type) {
+	case *IntLiteral:
+		return e.Value, true
+	case *BoolLiteral:
+		return e.Value, true
+	case *IdentExpr:
+		v, ok := env[e.Name]
+		return v, ok
+	case *ParenExpr:
+		return evalConst(e.Inner, env)
+	case *UnaryExpr:
+		v, ok := evalConst(e.Expr, env)
+		if !ok {
+			return nil, false
+		}
+		switch v := v.(type) {
+		case int:
+			if e.Op == UnaryNeg {
+				return -v, true
+			}
+		case bool:
+			if e.Op == UnaryNot {
+				return !v, true
+			}
+		}
+	case *BinaryExpr:
+		l, ok := evalConst(e.Left, env)
+		if !ok {
+			return nil, false
+		}
+		r, ok := evalConst(e.Right, env)
+		if !ok {
+			return nil, false
+		}
+		return evalBinary(e.Op, l, r)
+	}
+	return nil, false
+}
+
+func evalBinary(op BinOp, l, r any) (any, bool) {
+	if l, ok := l.(bool); ok {
+		r, ok := r.(bool)
+		if !ok {
+			return nil, false
+		}
+		switch op {
+		case BinAnd:
+			return l && r, true
+		case BinOr:
+			return l || r, true
+		case BinEq:
+			return l == r, true
+		case BinNeq:
+			return l != r, true
+		}
+		return nil, false
+	}
+
+	li, lok := l.(int)
+	ri, rok := r.(int)
+	if !lok || !rok || overflows(op, li, ri) {
+		return nil, false
+	}
+	switch op {
+	case BinAdd:
+		return li + ri, true
+	case BinSub:
+		return li - ri, true
+	case BinMul:
+		return li * ri, true
+	case BinDiv, BinMod:
+		if ri == 0 {
+			return nil, false
+		}
+		if op == BinDiv {
+			return li / ri, true
+		}
+		return li % ri, true
+	case BinEq:
+		return li == ri, true
+	case BinNeq:
+		return li != ri, true
+	case BinLT:
+		return li < ri, true
+	case BinGT:
+		return li > ri, true
+	}
+	return nil, false
+}
+
+// constOperands gives the values of e's operands when both are integer
+// constants.
+func constOperands(e *BinaryExpr) (l, r int, ok bool) {
+	lv, _ := evalConst(e.Left, nil)
+	rv, _ := evalConst(e.Right, nil)
+	l, lok := lv.(int)
+	r, rok := rv.(int)
+	return l, r, lok && rok
+}
+
+// overflows reports whether l op r doesn't fit in an integer, so that a
+// constant expression is never computed with a wrapped-around value.
+func overflows(op BinOp, l, r int) bool {
+	switch op {
+	case BinAdd:
+		return r > 0 && l > math.MaxInt-r || r < 0 && l < math.MinInt-r
+	case BinSub:
+		return r < 0 && l > math.MaxInt+r || r > 0 && l < math.MinInt+r
+	case BinMul:
+		if l == 0 || r == 0 {
+			return false
+		}
+		p := l * r
+		return p/r != l || l == -1 && r == math.MinInt || r == -1 && l == math.MinInt
+	case BinDiv, BinMod:
+		return l == math.MinInt && r == -1
+	}
+	return false
+}
+
+// AnalyzeWithWarnings is AnalyzeWithDiagnostics with the errors and the
+// warnings returned apart.
+func AnalyzeWithWarnings(p *Program) (errs, warnings []error) {
+	d := AnalyzeWithDiagnostics(p)
+	return d.Errors, d.Warnings
+}
+
+// unusedVars reports the parameters and locals of m that no expression
+// reads. Being assigned does not count as a use. for loop variables are
+// exempt since the loop itself reads them.
+func unusedVars(m *MethodDecl) []error {
+	if m.Body == nil { // extern
+		return nil
+	}
+	u := &usage{}
+	u.open()
+	for _, p := range m.Params {
+		u.declare(p.Name, lineOf(p))
+	}
+	u.block(m.Body)
+	u.close()
+
+	var warns []error
+	sort.SliceStable(u.vars, func(i, j int) bool { return u.vars[i].line < u.vars[j].line })
+	for _, v := range u.vars {
+		if !v.read {
+			warns = append(warns, fmt.Errorf("line %d: variable '%s' declared but never used", v.line, v.name))
+		}
+	}
+	return warns
+}
+
+// usage tracks which declared variables are read, one scope per block.
+type usage struct {
+	scopes []map[Identifier]*varUse
+	vars   []*varUse // every declaration, in declaration order
+}
+
+type varUse struct {
+	name Identifier
+	line int
+	read bool
+}
+
+func (u *usage) open()  { u.scopes = append(u.scopes, map[Identifier]*varUse{}) }
+func (u *usage) close() { u.scopes = u.scopes[:len(u.scopes)-1] }
+
+func (u *usage) declare(name Identifier, line int) *varUse {
+	v := &varUse{name: name, line: line}
+	u.scopes[len(u.scopes)-1][name] = v
+	u.vars = append(u.vars, v)
+	return v
+}
+
+// reads marks every variable e refers to as read. Names not declared in
+// the method (globals) are ignored.
+func (u *usage) reads(e Expr) {
+	if e == nil {
+		return
+	}
+	Walk(e, VisitorFunc(func(n Node) bool {
+		id, ok := n.(*IdentExpr)
+		if !ok {
+			return true
+		}
+		for i := len(u.scopes) - 1; i >= 0; i-- {
+			if v, ok := u.scopes[i][id.Name]; ok {
+				v.read = true
+				break
+			}
+		}
+		return true
+	}))
+}
+
+func (u *usage) block(b *Block) {
+	if b == nil {
+		return
+	}
+	u.open()
+	for _, d := range b.Declarations {
+		u.reads(d.Value) // the initializer sees the enclosing x, not this one
+		u.declare(d.Name, lineOf(d))
+	}
+	for _, st := range b.Stmts {
+		u.stmt(st)
+	}
+	u.close()
+}
+
+func (u *usage) stmt(st Stmt) {
+	switch st := st.(type) {
+	case *Block:
+		u.block(st)
+	case *Assignment:
+		u.reads(st.Value)
+	case *ExprStmt:
+		u.reads(st.Expr)
+	case *ReturnStmt:
+		u.reads(st.Value)
+	case *IfStmt:
+		u.reads(st.Cond)
+		u.block(st.Then)
+		u.block(st.Else)
+	case *WhileStmt:
+		u.reads(st.Cond)
+		u.block(st.Body)
+	case *ForStmt:
+		u.reads(st.Bound)
+		u.open()
+		if st.Var != nil {
+			u.reads(st.Var.Value)
+			u.declare(st.Var.Name, lineOf(st.Var)).read = true
+		}
+		u.block(st.Body)
+		u.close()
+	}
+}
@@ -0,0 +1,820 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// method wraps stmts in a method body; lines are whatever the statements
+// carry.
+func method(ret TypeKind, name string, stmts ...Stmt) *MethodDecl {
+	return &MethodDecl{
+		Return: &TypeNode{Kind: ret},
+		Name:   Identifier(name),
+		Body:   &Block{Stmts: stmts},
+	}
+}
+
+// withParam adds a parameter to m, for fixtures that read a name the
+// method doesn't otherwise declare.
+func withParam(m *MethodDecl, kind TypeKind, name string) *MethodDecl {
+	m.Params = append(m.Params, &Parameter{Type: &TypeNode{Kind: kind}, Name: Identifier(name)})
+	return m
+}
+
+func returnAt(line int) *ReturnStmt {
+	return &ReturnStmt{NodeBase: NodeBase{Line: line}, Value: NewIntLit(0)}
+}
+
+func callAt(line int) *ExprStmt {
+	return &ExprStmt{NodeBase: NodeBase{Line: line}, Expr: &CallExpr{Callee: "f"}}
+}
+
+func analyzeErrors(t *testing.T, methods ...*MethodDecl) []string {
+	t.Helper()
+	var msgs []string
+	for _, err := range AnalyzeWithDiagnostics(&Program{Methods: methods}).Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return msgs
+}
+
+func TestUnreachableAfterReturn(t *testing.T) {
+	got := analyzeErrors(t, method(TypeInteger, "f",
+		returnAt(3),
+		callAt(4),
+		callAt(5),
+	))
+	want := "line 4: unreachable code after return"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("expected [%s], got %v", want, got)
+	}
+}
+
+func TestUnreachableAfterIfWhenBothBranchesReturn(t *testing.T) {
+	both := &IfStmt{
+		NodeBase: NodeBase{Line: 3},
+		Cond:     NewBoolLit(true),
+		Then:     &Block{Stmts: []Stmt{returnAt(4)}},
+		Else:     &Block{Stmts: []Stmt{returnAt(6)}},
+	}
+	got := analyzeErrors(t, method(TypeInteger, "f", both, callAt(8)))
+	want := "line 8: unreachable code after return"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("expected [%s], got %v", want, got)
+	}
+}
+
+func TestMissingReturn(t *testing.T) {
+	onlyThen := &IfStmt{
+		Cond: &BinaryExpr{Left: NewIdent("y"), Op: BinGT, Right: NewIntLit(0)},
+		Then: &Block{Stmts: []Stmt{returnAt(3)}},
+	}
+	m := withParam(method(TypeInteger, "sign", onlyThen), TypeInteger, "y")
+	m.Line = 2
+	got := analyzeErrors(t, m)
+	want := "line 2: non-void method 'sign' may not return a value"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("expected [%s], got %v", want, got)
+	}
+}
+
+func TestAllPathsReturn(t *testing.T) {
+	both := &IfStmt{
+		Cond: &BinaryExpr{Left: NewIdent("y"), Op: BinGT, Right: NewIntLit(0)},
+		Then: &Block{Stmts: []Stmt{returnAt(3)}},
+		Else: &Block{Stmts: []Stmt{returnAt(5)}},
+	}
+	noReturn := &ExprStmt{Expr: &CallExpr{Callee: "yes"}}
+	got := analyzeErrors(t,
+		withParam(method(TypeInteger, "sign", both), TypeInteger, "y"),
+		method(TypeBool, "yes", &WhileStmt{Cond: NewBoolLit(true), Body: &Block{}}, &ReturnStmt{NodeBase: NodeBase{Line: 8}, Value: NewBoolLit(true)}),
+		method(TypeVoid, "main", noReturn),
+	)
+	if len(got) != 0 {
+		t.Errorf("expected no errors, got %v", got)
+	}
+}
+
+func TestReturnTypes(t *testing.T) {
+	ret := func(kind TypeKind, name string, value Expr) *MethodDecl {
+		return method(kind, name, &ReturnStmt{NodeBase: NodeBase{Line: 3}, Value: value})
+	}
+	rel := func(l Expr, op BinOp, r Expr) Expr { return &BinaryExpr{Left: l, Op: op, Right: r, Type: TypeBool} }
+
+	// bool isPositive(integer x) { return x > 0; }
+	isPositive := withParam(ret(TypeBool, "isPositive", rel(NewIdent("x"), BinGT, NewIntLit(0))), TypeInteger, "x")
+	if got := analyzeErrors(t, isPositive); len(got) != 0 {
+		t.Errorf("expected no errors, got %v", got)
+	}
+
+	tests := []struct {
+		m    *MethodDecl
+		want string
+	}{
+		{ret(TypeBool, "f", NewIntLit(1)), "line 3: cannot return a value of type integer from bool method 'f'"},
+		{withParam(ret(TypeInteger, "g", rel(NewIdent("x"), BinLT, NewIntLit(1))), TypeInteger, "x"), "line 3: cannot return a value of type bool from integer method 'g'"},
+		{ret(TypeInt8, "h", NewIntLit(1000)), "line 3: constant 1000 overflows int8 (-128 to 127)"},
+		{ret(TypeVoid, "main", NewIntLit(0)), "line 3: void method 'main' cannot return a value"},
+		{ret(TypeInteger, "k", nil), "line 3: method 'k' must return a value of type integer"},
+	}
+	for _, tt := range tests {
+		got := analyzeErrors(t, tt.m)
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("expected [%s], got %v", tt.want, got)
+		}
+	}
+}
+
+func TestNestedReturnTypes(t *testing.T) {
+	// bool f(integer n) { if (...) then { return 1; } while (...) { return 2; } for (...) { return 3; } return true; }
+	wrong := func(line int) *Block {
+		return &Block{Stmts: []Stmt{&ReturnStmt{NodeBase: NodeBase{Line: line}, Value: NewIntLit(line)}}}
+	}
+	cond := &BinaryExpr{Left: NewIdent("n"), Op: BinGT, Right: NewIntLit(0), Type: TypeBool}
+	m := withParam(method(TypeBool, "f",
+		&IfStmt{Cond: cond, Then: wrong(3)},
+		&WhileStmt{Cond: cond, Body: wrong(5)},
+		&ForStmt{
+			Var:   &VarDecl{Type: &TypeNode{Kind: TypeInteger}, Name: "i", Value: NewIntLit(0)},
+			Bound: NewIdent("n"),
+			Body:  wrong(7),
+		},
+		&ReturnStmt{NodeBase: NodeBase{Line: 9}, Value: NewBoolLit(true)},
+	), TypeInteger, "n")
+
+	got := analyzeErrors(t, m)
+	want := []string{
+		"line 3: cannot return a value of type integer from bool method 'f'",
+		"line 5: cannot return a value of type integer from bool method 'f'",
+		"line 7: cannot return a value of type integer from bool method 'f'",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestLoopForeverReturns(t *testing.T) {
+	// integer f() { while (true) { return 1; } }
+	forever := &WhileStmt{Cond: NewBoolLit(true), Body: &Block{Stmts: []Stmt{returnAt(3)}}}
+	if got := analyzeErrors(t, method(TypeInteger, "f", forever)); len(got) != 0 {
+		t.Errorf("expected no errors, got %v", got)
+	}
+
+	// The counter changes in the body, so the condition is not always true.
+	m := countdown(NewIntLit(0))
+	m.Return = &TypeNode{Kind: TypeInteger}
+	m.Line = 2
+	got := analyzeErrors(t, m)
+	if want := "line 2: non-void method 'f' may not return a value"; len(got) != 1 || got[0] != want {
+		t.Errorf("expected [%s], got %v", want, got)
+	}
+}
+
+func TestReachableAfterPartialReturns(t *testing.T) {
+	onlyThen := &IfStmt{
+		Cond: NewBoolLit(true),
+		Then: &Block{Stmts: []Stmt{returnAt(4)}},
+	}
+	loop := &WhileStmt{
+		Cond: NewBoolLit(true),
+		Body: &Block{Stmts: []Stmt{returnAt(7)}},
+	}
+	got := analyzeErrors(t, method(TypeInteger, "f", onlyThen, callAt(5), loop, returnAt(9)))
+	if len(got) != 0 {
+		t.Errorf("expected no errors, got %v", got)
+	}
+}
+
+func TestUnreachableInNestedBlock(t *testing.T) {
+	inner := &IfStmt{
+		Cond: NewBoolLit(true),
+		Then: &Block{Stmts: []Stmt{&ReturnStmt{NodeBase: NodeBase{Line: 4}}, callAt(5)}},
+	}
+	got := analyzeErrors(t, method(TypeVoid, "f", inner))
+	if len(got) != 1 || !strings.HasPrefix(got[0], "line 5:") {
+		t.Errorf("expected one error at line 5, got %v", got)
+	}
+}
+
+// analyzeWarnings runs the analyzer over methods and returns its warnings.
+func analyzeWarnings(methods ...*MethodDecl) []string {
+	var msgs []string
+	for _, w := range AnalyzeWithDiagnostics(&Program{Methods: methods}).Warnings {
+		msgs = append(msgs, w.Error())
+	}
+	return msgs
+}
+
+// countdown builds `integer i = <init>; <between> while (i < 5) { i = i + 1; }`.
+func countdown(init Expr, between ...Stmt) *MethodDecl {
+	loop := &WhileStmt{
+		NodeBase: NodeBase{Line: 4},
+		Cond:     &BinaryExpr{Left: NewIdent("i"), Op: BinLT, Right: NewIntLit(5)},
+		Body: &Block{Stmts: []Stmt{
+			&Assignment{Target: "i", Value: &BinaryExpr{Left: NewIdent("i"), Op: BinAdd, Right: NewIntLit(1)}},
+		}},
+	}
+	m := method(TypeVoid, "f", append(between, loop)...)
+	m.Body.Declarations = []*VarDecl{{Type: &TypeNode{Kind: TypeInteger}, Name: "i", Value: init}}
+	return m
+}
+
+func TestLoopNeverExecuted(t *testing.T) {
+	got := analyzeWarnings(countdown(NewIntLit(10)))
+	want := "line 4: loop body is never executed"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("expected [%s], got %v", want, got)
+	}
+}
+
+func TestLoopMayExecute(t *testing.T) {
+	tests := map[string]*MethodDecl{
+		"initially true": countdown(NewIntLit(0)),
+		"reassigned":     countdown(NewIntLit(10), &Assignment{Target: "i", Value: NewIntLit(2)}),
+		"unknown value":  countdown(&CallExpr{Callee: "g"}),
+		"assigned in if": countdown(NewIntLit(10), &IfStmt{
+			Cond: &CallExpr{Callee: "g"},
+			Then: &Block{Stmts: []Stmt{&Assignment{Target: "i", Value: NewIntLit(0)}}},
+		}),
+	}
+	for name, m := range tests {
+		if got := analyzeWarnings(m); len(got) != 0 {
+			t.Errorf("%s: expected no warnings, got %v", name, got)
+		}
+	}
+}
+
+func TestLoopOnGlobalMayExecute(t *testing.T) {
+	// bool done = true; void f() { done = false; reset(); while (done) {} }:
+	// reset may set done, so the loop may run.
+	m := method(TypeVoid, "f",
+		&Assignment{Target: "done", Value: NewBoolLit(false)},
+		&ExprStmt{Expr: &CallExpr{Callee: "reset"}},
+		&WhileStmt{NodeBase: NodeBase{Line: 5}, Cond: NewIdent("done"), Body: &Block{}},
+	)
+	p := &Program{
+		Declarations: []*VarDecl{{Type: &TypeNode{Kind: TypeBool}, Name: "done", Value: NewBoolLit(true)}},
+		Methods:      []*MethodDecl{method(TypeVoid, "reset"), m},
+	}
+	if got := AnalyzeWithDiagnostics(p).Warnings; len(got) != 0 {
+		t.Errorf("expected no warnings, got %v", got)
+	}
+}
+
+func TestAnalyzeKeepsWarningsApart(t *testing.T) {
+	// An unreachable statement (error) after a loop that never runs (warning).
+	m := countdown(NewIntLit(10))
+	m.Body.Stmts = append(m.Body.Stmts, &ReturnStmt{NodeBase: NodeBase{Line: 6}}, callAt(7))
+	p := &Program{Methods: []*MethodDecl{m}}
+
+	d := AnalyzeWithDiagnostics(p)
+	if len(d.Errors) != 1 || len(d.Warnings) != 1 {
+		t.Fatalf("expected 1 error and 1 warning, got errors %v, warnings %v", d.Errors, d.Warnings)
+	}
+	if got := d.Warnings[0].Error(); got != "line 4: loop body is never executed" {
+		t.Errorf("unexpected warning %q", got)
+	}
+
+	err := Analyze(p)
+	if err == nil || err.Error() != d.Errors[0].Error() {
+		t.Errorf("Analyze should return only the error, got %v", err)
+	}
+}
+
+func TestUnusedVariables(t *testing.T) {
+	decl := func(name string, line int) *VarDecl {
+		return &VarDecl{NodeBase: NodeBase{Line: line}, Type: &TypeNode{Kind: TypeInteger}, Name: Identifier(name), Value: NewIntLit(0)}
+	}
+	m := method(TypeInteger, "f",
+		&Assignment{Target: "assigned", Value: NewIntLit(5)},
+		&ReturnStmt{Value: &BinaryExpr{Left: NewIdent("used"), Op: BinAdd, Right: NewIdent("y")}},
+	)
+	m.Params = []*Parameter{
+		{NodeBase: NodeBase{Line: 1}, Type: &TypeNode{Kind: TypeInteger}, Name: "y"},
+		{NodeBase: NodeBase{Line: 1}, Type: &TypeNode{Kind: TypeInteger}, Name: "z"},
+	}
+	m.Body.Declarations = []*VarDecl{decl("unused", 2), decl("assigned", 3), decl("used", 4)}
+
+	errs, warnings := AnalyzeWithWarnings(&Program{Methods: []*MethodDecl{m}})
+	if len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+	want := []string{
+		"line 1: variable 'z' declared but never used",
+		"line 2: variable 'unused' declared but never used",
+		"line 3: variable 'assigned' declared but never used",
+	}
+	var got []string
+	for _, w := range warnings {
+		got = append(got, w.Error())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestUnusedVariablesRespectsShadowing(t *testing.T) {
+	// integer x = 1; { integer x = 2; return x; }: the outer x is never read.
+	inner := &Block{
+		Declarations: []*VarDecl{{NodeBase: NodeBase{Line: 3}, Type: &TypeNode{Kind: TypeInteger}, Name: "x", Value: NewIntLit(2)}},
+		Stmts:        []Stmt{&ReturnStmt{Value: NewIdent("x")}},
+	}
+	m := method(TypeInteger, "f", inner)
+	m.Body.Declarations = []*VarDecl{{NodeBase: NodeBase{Line: 2}, Type: &TypeNode{Kind: TypeInteger}, Name: "x", Value: NewIntLit(1)}}
+
+	_, warnings := AnalyzeWithWarnings(&Program{Methods: []*MethodDecl{m}})
+	if len(warnings) != 1 || warnings[0].Error() != "line 2: variable 'x' declared but never used" {
+		t.Errorf("expected only the outer x to be unused, got %v", warnings)
+	}
+}
+
+func TestStringPlacement(t *testing.T) {
+	str := func(line int) *StringLiteral {
+		return &StringLiteral{NodeBase: NodeBase{Line: line}, Value: "hi", Type: TypeString}
+	}
+	decl := func(kind TypeKind, value Expr) *VarDecl {
+		return &VarDecl{NodeBase: NodeBase{Line: 2}, Type: &TypeNode{Kind: kind}, Name: "s", Value: value}
+	}
+
+	print := &MethodDecl{
+		Return: &TypeNode{Kind: TypeVoid},
+		Name:   "print",
+		Params: []*Parameter{{Type: &TypeNode{Kind: TypeString}, Name: "s"}},
+		Extern: true,
+	}
+	ok := method(TypeVoid, "f",
+		&ExprStmt{Expr: &CallExpr{Callee: "print", Args: []Expr{str(3)}}},
+		&Assignment{Target: "s", Value: str(4)},
+	)
+	ok.Body.Declarations = []*VarDecl{decl(TypeString, str(2))}
+	if got := analyzeErrors(t, print, ok); len(got) != 0 {
+		t.Errorf("expected no errors, got %v", got)
+	}
+
+	tests := []struct {
+		name string
+		m    *MethodDecl
+		want string
+	}{
+		{
+			"operand",
+			withParam(method(TypeVoid, "f", &Assignment{Target: "s", Value: &BinaryExpr{Left: str(3), Op: BinAdd, Right: NewIntLit(1)}}), TypeString, "s"),
+			"line 3: a string can only be passed to a method or assigned to a string variable",
+		},
+		{
+			"condition",
+			method(TypeVoid, "f", &WhileStmt{Cond: str(3), Body: &Block{}}),
+			"line 3: a string can only be passed to a method or assigned to a string variable",
+		},
+		{
+			"returned",
+			method(TypeInteger, "f", &ReturnStmt{Value: str(3)}),
+			"line 3: a string can only be passed to a method or assigned to a string variable",
+		},
+	}
+	for _, tt := range tests {
+		got := analyzeErrors(t, tt.m)
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s: expected [%s], got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestStringVariables(t *testing.T) {
+	// string s = "hi"; integer n = 0; with s used where only integers go.
+	use := func(stmt Stmt) *MethodDecl {
+		m := method(TypeVoid, "f", stmt)
+		m.Body.Declarations = []*VarDecl{
+			{Type: &TypeNode{Kind: TypeString}, Name: "s", Value: &StringLiteral{Value: "hi", Type: TypeString}},
+			{Type: &TypeNode{Kind: TypeInteger}, Name: "n", Value: NewIntLit(0)},
+		}
+		return m
+	}
+	s := &IdentExpr{NodeBase: NodeBase{Line: 4}, Name: "s"}
+	inc := method(TypeVoid, "inc")
+	inc.Params = []*Parameter{{Type: &TypeNode{Kind: TypeInteger}, Name: "x"}}
+
+	tests := []struct {
+		name string
+		m    *MethodDecl
+		want string
+	}{
+		{
+			"arithmetic",
+			use(&Assignment{Target: "n", Value: &BinaryExpr{Left: s, Op: BinAdd, Right: NewIntLit(1), Type: TypeInteger}}),
+			"line 4: a string can only be passed to a method or assigned to a string variable",
+		},
+		{
+			"argument",
+			use(&ExprStmt{Expr: &CallExpr{NodeBase: NodeBase{Line: 4}, Callee: "inc", Args: []Expr{s}}}),
+			"line 4: argument 1 of 'inc' must be integer, got string",
+		},
+		{
+			"assignment",
+			use(&Assignment{NodeBase: NodeBase{Line: 4}, Target: "n", Value: s}),
+			"line 4: cannot assign a value of type string to integer variable 'n'",
+		},
+	}
+	for _, tt := range tests {
+		got := analyzeErrors(t, inc, tt.m)
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s: expected [%s], got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestStringDeclarationTypes(t *testing.T) {
+	p := &Program{Declarations: []*VarDecl{
+		{NodeBase: NodeBase{Line: 2}, Type: &TypeNode{Kind: TypeInteger}, Name: "n", Value: &StringLiteral{Value: "1", Type: TypeString}},
+		{NodeBase: NodeBase{Line: 3}, Type: &TypeNode{Kind: TypeString}, Name: "s", Value: NewBoolLit(true)},
+	}}
+	var got []string
+	for _, err := range AnalyzeWithDiagnostics(p).Errors {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"line 2: cannot initialize integer variable 'n' with a value of type string",
+		"line 3: cannot initialize string variable 's' with a value of type bool",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestCharComparisons(t *testing.T) {
+	char := func(r rune) *CharLiteral { return &CharLiteral{Value: r, Type: TypeChar} }
+	cmp := func(l Expr, op BinOp, r Expr) *MethodDecl {
+		return method(TypeBool, "f", &ReturnStmt{Value: &BinaryExpr{NodeBase: NodeBase{Line: 3}, Left: l, Op: op, Right: r}})
+	}
+
+	if got := analyzeErrors(t, cmp(char('a'), BinEq, char('b')), withParam(cmp(char('a'), BinNeq, NewIdent("c")), TypeChar, "c")); len(got) != 0 {
+		t.Errorf("expected no errors, got %v", got)
+	}
+
+	tests := []struct {
+		m    *MethodDecl
+		want string
+	}{
+		{cmp(char('a'), BinEq, NewIntLit(97)), "line 3: cannot compare char with integer"},
+		{cmp(NewBoolLit(true), BinNeq, char('a')), "line 3: cannot compare bool with char"},
+	}
+	for _, tt := range tests {
+		got := analyzeErrors(t, tt.m)
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("expected [%s], got %v", tt.want, got)
+		}
+	}
+
+	p := &Program{Declarations: []*VarDecl{
+		{NodeBase: NodeBase{Line: 2}, Type: &TypeNode{Kind: TypeChar}, Name: "c", Value: NewIntLit(97)},
+	}}
+	errs := AnalyzeWithDiagnostics(p).Errors
+	if want := "line 2: cannot initialize char variable 'c' with a value of type integer"; len(errs) != 1 || errs[0].Error() != want {
+		t.Errorf("expected [%s], got %v", want, errs)
+	}
+}
+
+func TestOperandTypes(t *testing.T) {
+	ret := func(l Expr, op BinOp, r Expr) *MethodDecl {
+		return method(TypeVoid, "f", &ExprStmt{Expr: &BinaryExpr{NodeBase: NodeBase{Line: 3}, Left: l, Op: op, Right: r}})
+	}
+
+	ok := []*MethodDecl{
+		ret(NewIntLit(7), BinMod, NewIntLit(2)),
+		ret(NewIntLit(1), BinLT, NewIntLit(2)),
+		ret(NewIntLit(1), BinNeq, NewIntLit(2)),
+		ret(NewBoolLit(true), BinNeq, NewBoolLit(false)),
+		withParam(ret(NewIdent("x"), BinMod, NewIntLit(2)), TypeInteger, "x"),
+	}
+	if got := analyzeErrors(t, ok...); len(got) != 0 {
+		t.Errorf("expected no errors, got %v", got)
+	}
+
+	tests := []struct {
+		m    *MethodDecl
+		want string
+	}{
+		{ret(NewBoolLit(true), BinMod, NewIntLit(2)), "line 3: operand of '%' must be integer, got bool"},
+		{ret(NewIntLit(7), BinMod, NewBoolLit(false)), "line 3: operand of '%' must be integer, got bool"},
+		{ret(NewIntLit(1), BinNeq, NewBoolLit(true)), "line 3: cannot compare integer with bool"},
+		{ret(NewIntLit(1), BinAnd, NewBoolLit(true)), "line 3: operand of '&&' must be bool, got integer"},
+		{ret(NewBoolLit(true), BinLT, NewBoolLit(false)), "line 3: operand of '<' must be integer, got bool"},
+		{ret(&CharLiteral{Value: 'a', Type: TypeChar}, BinGT, &CharLiteral{Value: 'b', Type: TypeChar}), "line 3: operand of '>' must be integer, got char"},
+	}
+	for _, tt := range tests {
+		got := analyzeErrors(t, tt.m)
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("expected [%s], got %v", tt.want, got)
+		}
+	}
+}
+
+func TestForLoopTypes(t *testing.T) {
+	loop := func(kind TypeKind, init, bound Expr) *MethodDecl {
+		return method(TypeVoid, "f", &ForStmt{
+			NodeBase: NodeBase{Line: 3},
+			Var:      &VarDecl{NodeBase: NodeBase{Line: 3}, Type: &TypeNode{Kind: kind}, Name: "i", Value: init},
+			Bound:    bound,
+			Body:     &Block{},
+		})
+	}
+
+	if got := analyzeErrors(t, loop(TypeInteger, NewIntLit(0), NewIntLit(10)), withParam(loop(TypeInt8, NewIntLit(0), NewIdent("n")), TypeInteger, "n")); len(got) != 0 {
+		t.Errorf("expected no errors, got %v", got)
+	}
+
+	// for (bool b = true; false)
+	got := analyzeErrors(t, loop(TypeBool, NewBoolLit(true), NewBoolLit(false)))
+	want := []string{
+		"line 3: for loop bound must be integer, got bool",
+		"line 3: for loop variable 'i' must be integer, got bool",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestAssignToMethod(t *testing.T) {
+	helper := method(TypeInteger, "helper", returnAt(2))
+	main := method(TypeVoid, "main", &Assignment{NodeBase: NodeBase{Line: 5}, Target: "helper", Value: NewIntLit(1)})
+
+	got := analyzeErrors(t, helper, main)
+	want := "line 5: cannot assign to method 'helper'"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("expected [%s], got %v", want, got)
+	}
+
+	// A local of the same name shadows the method.
+	main.Body.Declarations = []*VarDecl{{Type: &TypeNode{Kind: TypeInteger}, Name: "helper", Value: NewIntLit(0)}}
+	if got := analyzeErrors(t, helper, main); len(got) != 0 {
+		t.Errorf("expected no errors with a shadowing local, got %v", got)
+	}
+}
+
+func TestCallVariable(t *testing.T) {
+	// integer x = 0; void main() { x(1, 2); }
+	call := &CallExpr{NodeBase: NodeBase{Line: 4}, Callee: "x", Args: []Expr{NewIntLit(1), NewIntLit(2)}}
+	p := &Program{
+		Declarations: []*VarDecl{{Type: &TypeNode{Kind: TypeInteger}, Name: "x", Value: NewIntLit(0)}},
+		Methods:      []*MethodDecl{method(TypeVoid, "main", &ExprStmt{Expr: call})},
+	}
+
+	errs := AnalyzeWithDiagnostics(p).Errors
+	if want := "line 4: 'x' is not a method"; len(errs) != 1 || errs[0].Error() != want {
+		t.Errorf("expected [%s], got %v", want, errs)
+	}
+}
+
+func TestCallArguments(t *testing.T) {
+	param := func(name string) *Parameter {
+		return &Parameter{Type: &TypeNode{Kind: TypeInteger}, Name: Identifier(name)}
+	}
+	sum := method(TypeInteger, "sum", returnAt(2))
+	sum.Params = []*Parameter{param("a"), param("b")}
+	call := func(line int, args ...Expr) Stmt {
+		return &ExprStmt{Expr: &CallExpr{NodeBase: NodeBase{Line: line}, Callee: "sum", Args: args}}
+	}
+
+	if got := analyzeErrors(t, sum, withParam(method(TypeVoid, "main", call(5, NewIntLit(1), NewIdent("x"))), TypeInteger, "x")); len(got) != 0 {
+		t.Errorf("expected a correct call to check, got %v", got)
+	}
+
+	got := analyzeErrors(t, sum, method(TypeVoid, "main",
+		call(5, NewIntLit(1)),
+		call(6, NewIntLit(1), &StringLiteral{Value: "2", Type: TypeString}),
+		call(7, NewBoolLit(true), NewIntLit(1)),
+	))
+	want := []string{
+		"line 5: method 'sum' expects 2 arguments, got 1",
+		"line 6: argument 2 of 'sum' must be integer, got string",
+		"line 7: argument 1 of 'sum' must be integer, got bool",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors mismatch:\n got %q\nwant %q", got, want)
+	}
+
+	// sum(
+	//     true,
+	//     "2");
+	// reports each argument on its own line.
+	got = analyzeErrors(t, sum, method(TypeVoid, "main", call(5,
+		&BoolLiteral{NodeBase: NodeBase{Line: 6}, Value: true, Type: TypeBool},
+		&StringLiteral{NodeBase: NodeBase{Line: 7}, Value: "2", Type: TypeString},
+	)))
+	want = []string{
+		"line 6: argument 1 of 'sum' must be integer, got bool",
+		"line 7: argument 2 of 'sum' must be integer, got string",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestUndeclaredVariable(t *testing.T) {
+	// void main() { total = 1; if (done) then { } while (count > 0) { } }
+	main := method(TypeVoid, "main",
+		&Assignment{NodeBase: NodeBase{Line: 2}, Target: "total", Value: NewIntLit(1)},
+		&IfStmt{Cond: &IdentExpr{NodeBase: NodeBase{Line: 3}, Name: "done"}, Then: &Block{}},
+		&WhileStmt{Cond: &BinaryExpr{Left: &IdentExpr{NodeBase: NodeBase{Line: 4}, Name: "count"}, Op: BinGT, Right: NewIntLit(0)}, Body: &Block{}},
+	)
+
+	got := analyzeErrors(t, main)
+	want := []string{
+		"line 2: undeclared variable 'total'",
+		"line 3: undeclared variable 'done'",
+		"line 4: undeclared variable 'count'",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestUndeclaredMethod(t *testing.T) {
+	// void main() { foo(1); integer n = bar(); }
+	main := method(TypeVoid, "main",
+		&ExprStmt{Expr: &CallExpr{NodeBase: NodeBase{Line: 3}, Callee: "foo", Args: []Expr{NewIntLit(1)}}},
+	)
+	main.Body.Declarations = []*VarDecl{
+		{NodeBase: NodeBase{Line: 2}, Type: &TypeNode{Kind: TypeInteger}, Name: "n", Value: &CallExpr{NodeBase: NodeBase{Line: 2}, Callee: "bar"}},
+	}
+
+	got := analyzeErrors(t, main)
+	want := []string{
+		"line 2: undeclared method 'bar'",
+		"line 3: undeclared method 'foo'",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestAnalyzeReadsProgramSymbols(t *testing.T) {
+	// p.Symbols says x is a variable, even though the AST has no
+	// declaration for it: the analyzer must trust the builder's scope.
+	call := &CallExpr{NodeBase: NodeBase{Line: 3}, Callee: "x"}
+	p := &Program{Methods: []*MethodDecl{method(TypeVoid, "main", &ExprStmt{Expr: call})}}
+	p.Symbols.Push()
+	p.Symbols.Insert("x", varSymbol(&TypeNode{Kind: TypeInteger}, 1))
+
+	errs := AnalyzeWithDiagnostics(p).Errors
+	if want := "line 3: 'x' is not a method"; len(errs) != 1 || errs[0].Error() != want {
+		t.Errorf("expected [%s], got %v", want, errs)
+	}
+	if len(p.Symbols) != 1 {
+		t.Errorf("expected the analysis to leave p.Symbols with one scope, got %d", len(p.Symbols))
+	}
+}
+
+func TestExternCallArguments(t *testing.T) {
+	// extern integer foo(integer);
+	foo := &MethodDecl{
+		Return: &TypeNode{Kind: TypeInteger},
+		Name:   "foo",
+		Params: []*Parameter{{Type: &TypeNode{Kind: TypeInteger}, Name: "n"}},
+		Extern: true,
+	}
+	call := func(line int, args ...Expr) Stmt {
+		return &ExprStmt{Expr: &CallExpr{NodeBase: NodeBase{Line: line}, Callee: "foo", Args: args}}
+	}
+
+	if got := analyzeErrors(t, foo, method(TypeVoid, "main", call(4, NewIntLit(1)))); len(got) != 0 {
+		t.Errorf("expected a correct extern call to check, got %v", got)
+	}
+
+	got := analyzeErrors(t, foo, method(TypeVoid, "main",
+		call(4, NewBoolLit(true)),
+		call(5),
+	))
+	want := []string{
+		"line 4: argument 1 of 'foo' must be integer, got bool",
+		"line 5: method 'foo' expects 1 arguments, got 0",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestIntegerRange(t *testing.T) {
+	neg := func(v int) Expr { return &UnaryExpr{Op: UnaryNeg, Expr: NewIntLit(v), Type: TypeInteger} }
+	sum := func(l, r int) Expr {
+		return &BinaryExpr{Left: NewIntLit(l), Op: BinAdd, Right: NewIntLit(r), Type: TypeInteger}
+	}
+	tests := []struct {
+		kind  TypeKind
+		value Expr
+		want  string // "" when the value fits
+	}{
+		{TypeInt8, NewIntLit(127), ""},
+		{TypeInt8, NewIntLit(128), "line 2: constant 128 overflows int8 (-128 to 127)"},
+		{TypeInt8, neg(128), ""},
+		{TypeInt8, neg(129), "line 2: constant -129 overflows int8 (-128 to 127)"},
+		{TypeInt8, sum(100, 100), "line 2: constant 200 overflows int8 (-128 to 127)"},
+		{TypeInt16, NewIntLit(32767), ""},
+		{TypeInt16, NewIntLit(32768), "line 2: constant 32768 overflows int16 (-32768 to 32767)"},
+		{TypeInt16, neg(32769), "line 2: constant -32769 overflows int16 (-32768 to 32767)"},
+		{TypeInt32, NewIntLit(2147483647), ""},
+		{TypeInt32, NewIntLit(2147483648), "line 2: constant 2147483648 overflows int32 (-2147483648 to 2147483647)"},
+		{TypeInt32, neg(2147483649), "line 2: constant -2147483649 overflows int32 (-2147483648 to 2147483647)"},
+		{TypeInt64, NewIntLit(1 << 62), ""},
+		{TypeInteger, NewIntLit(1 << 62), ""},
+	}
+	for _, tt := range tests {
+		p := &Program{Declarations: []*VarDecl{{NodeBase: NodeBase{Line: 2}, Type: &TypeNode{Kind: tt.kind}, Name: "x", Value: tt.value}}}
+		var got []string
+		for _, err := range AnalyzeWithDiagnostics(p).Errors {
+			got = append(got, err.Error())
+		}
+		if tt.want == "" && len(got) != 0 || tt.want != "" && (len(got) != 1 || got[0] != tt.want) {
+			t.Errorf("%s x = %#v: expected [%s], got %v", tt.kind, tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestConstantOverflow(t *testing.T) {
+	bin := func(l Expr, op BinOp, r Expr) Expr {
+		return &BinaryExpr{NodeBase: NodeBase{Line: 2}, Left: l, Op: op, Right: r, Type: TypeInteger}
+	}
+	maxInt := NewIntLit(math.MaxInt)
+	tests := []struct {
+		value Expr
+		want  string // "" when the value fits
+	}{
+		{bin(maxInt, BinAdd, NewIntLit(0)), ""},
+		{bin(maxInt, BinAdd, NewIntLit(1)), "line 2: integer overflow in constant expression"},
+		{bin(NewIntLit(math.MinInt), BinSub, NewIntLit(1)), "line 2: integer overflow in constant expression"},
+		{bin(NewIntLit(1<<31), BinMul, NewIntLit(1<<31)), ""},
+		{bin(NewIntLit(1<<32), BinMul, NewIntLit(1<<32)), "line 2: integer overflow in constant expression"},
+		// Only the innermost operation that overflows is reported.
+		{bin(bin(maxInt, BinMul, NewIntLit(2)), BinAdd, NewIntLit(1)), "line 2: integer overflow in constant expression"},
+	}
+	for _, tt := range tests {
+		p := &Program{Declarations: []*VarDecl{{NodeBase: NodeBase{Line: 2}, Type: &TypeNode{Kind: TypeInteger}, Name: "x", Value: tt.value}}}
+		var got []string
+		for _, err := range AnalyzeWithDiagnostics(p).Errors {
+			got = append(got, err.Error())
+		}
+		if tt.want == "" && len(got) != 0 || tt.want != "" && (len(got) != 1 || got[0] != tt.want) {
+			t.Errorf("x = %#v: expected [%s], got %v", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	// integer f(integer x) { integer z = 0; integer q = x % z; return x / z; }
+	div := func(line int, op BinOp) Expr {
+		return &BinaryExpr{NodeBase: NodeBase{Line: line}, Left: NewIdent("x"), Op: op, Right: NewIdent("z"), Type: TypeInteger}
+	}
+	m := withParam(method(TypeInteger, "f",
+		&ReturnStmt{Value: div(4, BinDiv)},
+	), TypeInteger, "x")
+	m.Body.Declarations = []*VarDecl{
+		{NodeBase: NodeBase{Line: 2}, Type: &TypeNode{Kind: TypeInteger}, Name: "z", Value: NewIntLit(0)},
+		{NodeBase: NodeBase{Line: 3}, Type: &TypeNode{Kind: TypeInteger}, Name: "q", Value: div(3, BinMod)},
+	}
+	got := analyzeWarnings(m)
+	want := []string{
+		"line 3: division by zero",
+		"line 4: division by zero",
+		"line 3: variable 'q' declared but never used",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings mismatch:\n got %q\nwant %q", got, want)
+	}
+
+	// Once z is assigned a non-zero value the division is fine.
+	m.Body.Stmts = append([]Stmt{&Assignment{Target: "z", Value: NewIntLit(2)}}, m.Body.Stmts...)
+	got = analyzeWarnings(m)
+	want = []string{
+		"line 3: division by zero",
+		"line 3: variable 'q' declared but never used",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestIntegerNarrowing(t *testing.T) {
+	// int64 wide = 0; int8 b = 0; with b assigned from wide and from a
+	// constant that doesn't fit.
+	m := method(TypeVoid, "f",
+		&Assignment{NodeBase: NodeBase{Line: 4}, Target: "b", Value: NewIdent("wide")},
+		&Assignment{NodeBase: NodeBase{Line: 5}, Target: "b", Value: NewIntLit(1000)},
+		&Assignment{NodeBase: NodeBase{Line: 6}, Target: "b", Value: &BinaryExpr{Left: NewIdent("b"), Op: BinAdd, Right: NewIntLit(1), Type: TypeInteger}},
+		&Assignment{NodeBase: NodeBase{Line: 7}, Target: "wide", Value: NewIdent("b")},
+	)
+	m.Body.Declarations = []*VarDecl{
+		{NodeBase: NodeBase{Line: 2}, Type: &TypeNode{Kind: TypeInt64}, Name: "wide", Value: NewIntLit(0)},
+		{NodeBase: NodeBase{Line: 3}, Type: &TypeNode{Kind: TypeInt8}, Name: "b", Value: NewIdent("wide")},
+	}
+	got := analyzeErrors(t, m)
+	want := []string{
+		"line 3: cannot initialize int8 variable 'b' with a value of type int64",
+		"line 4: cannot assign a value of type int64 to int8 variable 'b'",
+		"line 5: constant 1000 overflows int8 (-128 to 127)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors mismatch:\n got %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// compileSummary is the -summary=json report: a single-line digest of one
+// compilation meant for CI dashboards. Field names are part of the format.
+type compileSummary struct {
+	File       string `json:"file"`
+	Methods    int    `json:"methods"`
+	Globals    int    `json:"globals"`
+	Statements int    `json:"statements"`
+	Errors     int    `json:"errors"`
+	Warnings   int    `json:"warnings"`
+	Succeeded  bool   `json:"succeeded"`
+}
+
+// summarize fills the AST-derived counts; p may be nil when building failed.
+// succeeded is whether the compilation exits with status zero, which with
+// -Werror also depends on the warnings.
+func summarize(file string, p *Program, errors, warnings int, succeeded bool) compileSummary {
+	s := compileSummary{
+		File:      file,
+		Errors:    errors,
+		Warnings:  warnings,
+		Succeeded: succeeded,
+	}
+	if p == nil {
+		return s
+	}
+	s.Globals = len(p.Declarations)
+	s.Methods = len(p.Methods)
+	for _, m := range p.Methods {
+		s.Statements += countStmts(m.Body)
+	}
+	return s
+}
+
+// countStmts counts declarations and statements in b, including those in
+// nested blocks. Blocks themselves are not counted, and a for counts once:
+// its loop variable is part of it, not a declaration of its own.
+func countStmts(b *Block) int {
+	if b == nil {
+		return 0
+	}
+	n := 0
+	Walk(b, VisitorFunc(func(node Node) bool {
+		switch node := node.(type) {
+		case *Block:
+		case *ForStmt:
+			n += 1 + countStmts(node.Body)
+			return false
+		case *VarDecl, Stmt:
+			n++
+		}
+		return true
+	}))
+	return n
+}
+
+func writeSummaryJSON(w io.Writer, s compileSummary) error {
+	out, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
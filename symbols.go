@@ -0,0 +1,90 @@
+package main
+
+// Symbol is what a declared name stands for: a variable (global, local or
+// parameter) or a method.
+type Symbol struct {
+	Type  TypeKind // the variable's type, or the method's return type
+	Line  int      // where the name is declared
+	isVar bool
+	Func  *FuncInfo // signature of a method; nil for variables
+}
+
+// FuncInfo is a method's signature, what calls are checked against.
+type FuncInfo struct {
+	Return TypeKind
+	Params []*Parameter
+	Arity  int
+}
+
+// Env is the stack of scopes active at some point of the program, the
+// top-level scope first. A name resolves to its nearest enclosing
+// declaration.
+type Env []map[Identifier]*Symbol
+
+// Push opens a new innermost scope.
+func (e *Env) Push() { *e = append(*e, map[Identifier]*Symbol{}) }
+
+// Pop closes the innermost scope.
+func (e *Env) Pop() { *e = (*e)[:len(*e)-1] }
+
+// Insert declares name in the innermost scope, shadowing any outer one.
+func (e Env) Insert(name Identifier, sym *Symbol) { e[len(e)-1][name] = sym }
+
+// Lookup finds the declaration name refers to, searching outwards.
+func (e Env) Lookup(name Identifier) (*Symbol, bool) {
+	for i := len(e) - 1; i >= 0; i-- {
+		if sym, ok := e[i][name]; ok {
+			return sym, true
+		}
+	}
+	return nil, false
+}
+
+// scopeOf returns the index in e of the scope that declares the name
+// Lookup would find, or -1 when name is not declared.
+func (e Env) scopeOf(name Identifier) int {
+	for i := len(e) - 1; i >= 0; i-- {
+		if _, ok := e[i][name]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// topLevel returns the scope holding p's globals and methods: p.Symbols as
+// the builder filled it, or a new one for an AST put together by hand.
+// Methods are in it from the start, so they can be called before they are
+// declared.
+func topLevel(p *Program) Env {
+	if len(p.Symbols) > 0 {
+		return p.Symbols
+	}
+	var env Env
+	env.Push()
+	for _, d := range p.Declarations {
+		env.Insert(d.Name, varSymbol(d.Type, lineOf(d)))
+	}
+	for _, m := range p.Methods {
+		env.Insert(m.Name, methodSymbol(m))
+	}
+	return env
+}
+
+// varSymbol is the symbol a variable declaration introduces.
+func varSymbol(t *TypeNode, line int) *Symbol {
+	sym := &Symbol{Line: line, isVar: true}
+	if t != nil {
+		sym.Type = t.Kind
+	}
+	return sym
+}
+
+// methodSymbol is the symbol a method declaration introduces, with its
+// signature.
+func methodSymbol(m *MethodDecl) *Symbol {
+	info := &FuncInfo{Params: m.Params, Arity: len(m.Params)}
+	if m.Return != nil {
+		info.Return = m.Return.Kind
+	}
+	return &Symbol{Type: info.Return, Line: lineOf(m), Func: info}
+}
@@ -0,0 +1,112 @@
+package main
+
+// Visitor is called by Walk for every node it reaches. Returning false
+// skips that node's children.
+type Visitor interface {
+	Visit(n Node) bool
+}
+
+// VisitorFunc adapts a plain function to the Visitor interface.
+type VisitorFunc func(Node) bool
+
+func (f VisitorFunc) Visit(n Node) bool { return f(n) }
+
+// Walk traverses the AST rooted at node depth-first, visiting each node
+// before its children. Children are visited in source order:
+//
+//	Program:    Declarations, then Methods
+//	VarDecl:    Type, Value
+//	Parameter:  Type
+//	MethodDecl: Return, Params, Body
+//	Block:      Declarations, then Stmts
+//	IfStmt:     Cond, Then, Else
+//	WhileStmt:  Cond, Body
+//	ForStmt:    Var, Bound, Body
+//	BinaryExpr: Left, Right
+//
+// Absent children (nil Else, Body of an extern, ...) are skipped. Names are
+// plain Identifiers and are not visited as nodes.
+func Walk(node Node, v Visitor) {
+	if node == nil || !v.Visit(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, d := range n.Declarations {
+			Walk(d, v)
+		}
+		for _, m := range n.Methods {
+			Walk(m, v)
+		}
+	case *VarDecl:
+		walkType(n.Type, v)
+		walkExpr(n.Value, v)
+	case *Parameter:
+		walkType(n.Type, v)
+	case *MethodDecl:
+		walkType(n.Return, v)
+		for _, p := range n.Params {
+			Walk(p, v)
+		}
+		walkBlock(n.Body, v)
+	case *Block:
+		for _, d := range n.Declarations {
+			Walk(d, v)
+		}
+		for _, s := range n.Stmts {
+			Walk(s, v)
+		}
+	case *Assignment:
+		walkExpr(n.Value, v)
+	case *ExprStmt:
+		walkExpr(n.Expr, v)
+	case *ReturnStmt:
+		walkExpr(n.Value, v)
+	case *IfStmt:
+		walkExpr(n.Cond, v)
+		walkBlock(n.Then, v)
+		walkBlock(n.Else, v)
+	case *WhileStmt:
+		walkExpr(n.Cond, v)
+		walkBlock(n.Body, v)
+	case *ForStmt:
+		if n.Var != nil {
+			Walk(n.Var, v)
+		}
+		walkExpr(n.Bound, v)
+		walkBlock(n.Body, v)
+	case *UnaryExpr:
+		walkExpr(n.Expr, v)
+	case *BinaryExpr:
+		walkExpr(n.Left, v)
+		walkExpr(n.Right, v)
+	case *CallExpr:
+		for _, a := range n.Args {
+			walkExpr(a, v)
+		}
+	case *ParenExpr:
+		walkExpr(n.Inner, v)
+	}
+}
+
+// The helpers below keep typed nil pointers (a nil *Block stored in a
+// Node) from reaching Walk as non-nil interfaces.
+
+func walkType(t *TypeNode, v Visitor) {
+	if t != nil {
+		Walk(t, v)
+	}
+}
+
+func walkBlock(b *Block, v Visitor) {
+	if b != nil {
+		Walk(b, v)
+	}
+}
+
+func walkExpr(e Expr, v Visitor) {
+	if e != nil {
+		Walk(e, v)
+	}
+}
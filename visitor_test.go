@@ -0,0 +1,115 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// everyKindProgram uses each AST node type at least once.
+func everyKindProgram() *Program {
+	integer := func() *TypeNode { return &TypeNode{Kind: TypeInteger} }
+	return &Program{
+		Declarations: []*VarDecl{
+			{Type: integer(), Name: "g", Value: NewIntLit(1)},
+		},
+		Methods: []*MethodDecl{
+			{Return: integer(), Name: "ext", Params: []*Parameter{{Type: integer(), Name: "a"}}, Extern: true},
+			{
+				Return: &TypeNode{Kind: TypeVoid},
+				Name:   "main",
+				Body: &Block{
+					Declarations: []*VarDecl{
+						{Type: &TypeNode{Kind: TypeBool}, Name: "b", Value: NewBoolLit(true)},
+						{Type: &TypeNode{Kind: TypeString}, Name: "s", Value: &StringLiteral{Value: "hi", Type: TypeString}},
+						{Type: &TypeNode{Kind: TypeChar}, Name: "c", Value: &CharLiteral{Value: 'a', Type: TypeChar}},
+					},
+					Stmts: []Stmt{
+						&Assignment{Target: "g", Value: &UnaryExpr{Op: UnaryNeg, Expr: NewIdent("g")}},
+						&IfStmt{
+							Cond: &BinaryExpr{Left: NewIdent("g"), Op: BinLT, Right: NewIntLit(0)},
+							Then: &Block{Stmts: []Stmt{&ExprStmt{Expr: &CallExpr{Callee: "ext", Args: []Expr{NewIdent("g")}}}}},
+						},
+						&WhileStmt{Cond: &ParenExpr{Inner: NewIdent("b")}, Body: &Block{}},
+						&ForStmt{
+							Var:   &VarDecl{Type: integer(), Name: "i", Value: NewIntLit(0)},
+							Bound: NewIdent("g"),
+							Body:  &Block{},
+						},
+						&ReturnStmt{},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestWalkOrder(t *testing.T) {
+	var got []string
+	Walk(everyKindProgram(), VisitorFunc(func(n Node) bool {
+		got = append(got, n.NodeType())
+		return true
+	}))
+
+	want := []string{
+		"Program",
+		"VarDecl", "Type", "IntLiteral",
+		"MethodDecl", "Type", "Parameter", "Type",
+		"MethodDecl", "Type", "Block",
+		"VarDecl", "Type", "BoolLiteral",
+		"VarDecl", "Type", "StringLiteral",
+		"VarDecl", "Type", "CharLiteral",
+		"Assignment", "UnaryExpr", "IdentExpr",
+		"IfStmt", "BinaryExpr", "IdentExpr", "IntLiteral",
+		"Block", "ExprStmt", "CallExpr", "IdentExpr",
+		"WhileStmt", "ParenExpr", "IdentExpr", "Block",
+		"ForStmt", "VarDecl", "Type", "IntLiteral", "IdentExpr", "Block",
+		"ReturnStmt",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("visit order mismatch:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestWalkVisitsEveryNodeKind(t *testing.T) {
+	seen := map[reflect.Type]bool{}
+	Walk(everyKindProgram(), VisitorFunc(func(n Node) bool {
+		seen[reflect.TypeOf(n)] = true
+		return true
+	}))
+
+	kinds := []Node{
+		&Program{}, &VarDecl{}, &Parameter{}, &MethodDecl{}, &TypeNode{},
+		&Block{}, &Assignment{}, &ExprStmt{}, &ReturnStmt{}, &IfStmt{}, &WhileStmt{}, &ForStmt{},
+		&IntLiteral{}, &BoolLiteral{}, &StringLiteral{}, &CharLiteral{}, &IdentExpr{}, &UnaryExpr{}, &BinaryExpr{},
+		&CallExpr{}, &ParenExpr{},
+	}
+	for _, k := range kinds {
+		if !seen[reflect.TypeOf(k)] {
+			t.Errorf("%s was never visited", k.NodeType())
+		}
+	}
+}
+
+func TestWalkSkipsChildrenWhenVisitReturnsFalse(t *testing.T) {
+	count := 0
+	Walk(everyKindProgram(), VisitorFunc(func(n Node) bool {
+		count++
+		_, isMethod := n.(*MethodDecl)
+		return !isMethod
+	}))
+	// Program, the global VarDecl with its Type and IntLiteral, and the two
+	// MethodDecls whose children are skipped.
+	if count != 6 {
+		t.Errorf("expected 6 visited nodes, got %d", count)
+	}
+}
+
+func TestCountStmtsNestedBlocks(t *testing.T) {
+	// b, s, c, the assignment, the if and its call statement, the while,
+	// the for (its loop variable is part of it), and the return; blocks
+	// themselves don't count.
+	body := everyKindProgram().Methods[1].Body
+	if got := countStmts(body); got != 9 {
+		t.Errorf("expected 9 statements, got %d", got)
+	}
+}